@@ -0,0 +1,121 @@
+package telebot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/berserktech/telebot/gh"
+)
+
+// approvalsTTL is how long the approvals of a pull request are remembered. The
+// ones merged later on are sent without a count.
+const approvalsTTL = 30 * 24 * time.Hour
+
+// approvals counts who approved each pull request, out of the reviews the bot
+// receives, since the payloads of the merged pull requests don't say it. With
+// summary, it also counts who requested changes, to sum up the reviews in every
+// message of the pull requests. What it remembers is kept in its store.
+type approvals struct {
+	store Store
+	// counts and summary are set by Config.ApprovalCounts and
+	// Config.ReviewSummary.
+	counts, summary bool
+
+	// mu keeps two reviews of the same pull request from being counted at
+	// the same time, since the store is read and then set.
+	mu sync.Mutex
+}
+
+func newApprovals(store Store, counts bool, summary bool) *approvals {
+	return &approvals{store: store, counts: counts, summary: summary}
+}
+
+// track remembers the approvals of the reviews, and forgets them once the
+// reviewers request changes or their reviews are dismissed. It returns the
+// message, saying how many reviewers approved the pull request if it was
+// merged, and with summary how many approved it and requested changes. Nothing
+// is said if the count is unknown.
+func (a *approvals) track(message gh.Message) gh.Message {
+	if message.PullRequest == "" {
+		return message
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	approved := a.reviewers("approvals:" + message.PullRequest)
+	changes := a.reviewers("changes:" + message.PullRequest)
+
+	changed := true
+	switch message.Review {
+	case "approved":
+		approved[message.Reviewer] = true
+		delete(changes, message.Reviewer)
+	case "changes_requested":
+		delete(approved, message.Reviewer)
+		changes[message.Reviewer] = true
+	case "dismissed":
+		delete(approved, message.Reviewer)
+		delete(changes, message.Reviewer)
+	default:
+		changed = false
+	}
+	if changed {
+		a.set("approvals:"+message.PullRequest, approved)
+		if a.summary {
+			a.set("changes:"+message.PullRequest, changes)
+		}
+	}
+
+	if message.Text == "" {
+		return message
+	}
+	if a.counts && message.Merged && len(approved) > 0 {
+		message.Text += fmt.Sprintf(" (approved by %d %s)", len(approved), plural(len(approved), "reviewer"))
+	}
+	if a.summary && len(approved)+len(changes) > 0 {
+		var summary []string
+		if len(approved) > 0 {
+			summary = append(summary, fmt.Sprintf("%d approved", len(approved)))
+		}
+		if len(changes) > 0 {
+			summary = append(summary, fmt.Sprintf("%d changes requested", len(changes)))
+		}
+		message.Text += "\nreviews: " + strings.Join(summary, ", ")
+	}
+	return message
+}
+
+// reviewers returns the logins of the reviewers remembered at the key.
+func (a *approvals) reviewers(key string) map[string]bool {
+	value, _ := a.store.Get(key)
+	reviewers := map[string]bool{}
+	for _, reviewer := range strings.Fields(value) {
+		reviewers[reviewer] = true
+	}
+	return reviewers
+}
+
+// set remembers the logins of the reviewers at the key, sorted.
+func (a *approvals) set(key string, reviewers map[string]bool) {
+	var logins []string
+	for reviewer := range reviewers {
+		logins = append(logins, reviewer)
+	}
+	sort.Strings(logins)
+	if err := a.store.Set(key, strings.Join(logins, " "), approvalsTTL); err != nil {
+		log.Print(err)
+	}
+}
+
+// plural returns the word, with an "s" unless there's one.
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
@@ -0,0 +1,73 @@
+package telebot
+
+import (
+	"github.com/berserktech/telebot/gh"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const approvalsPR = "https://github.com/Codertocat/Hello-World/pull/1"
+
+func TestApprovalsMerged(t *testing.T) {
+	a := newApprovals(NewMemoryStore(), true, false)
+
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "approved", Reviewer: "alice"})
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "approved", Reviewer: "bob"})
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "approved", Reviewer: "bob"})
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "commented", Reviewer: "carol"})
+
+	merged := a.track(gh.Message{Text: "merged", PullRequest: approvalsPR, Merged: true})
+	assert.Equal(t, "merged (approved by 2 reviewers)", merged.Text)
+}
+
+func TestApprovalsChangesRequested(t *testing.T) {
+	a := newApprovals(NewMemoryStore(), true, false)
+
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "approved", Reviewer: "alice"})
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "approved", Reviewer: "bob"})
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "changes_requested", Reviewer: "bob"})
+
+	merged := a.track(gh.Message{Text: "merged", PullRequest: approvalsPR, Merged: true})
+	assert.Equal(t, "merged (approved by 1 reviewer)", merged.Text)
+}
+
+func TestApprovalsUnknown(t *testing.T) {
+	a := newApprovals(NewMemoryStore(), true, false)
+
+	merged := a.track(gh.Message{Text: "merged", PullRequest: approvalsPR, Merged: true})
+	assert.Equal(t, "merged", merged.Text)
+}
+
+func TestApprovalsStore(t *testing.T) {
+	// The approvals are kept as they were before the summary, so that the
+	// ones remembered in a STATE_DIR still count.
+	store := NewMemoryStore()
+	assert.Nil(t, store.Set("approvals:"+approvalsPR, "alice bob", approvalsTTL))
+	a := newApprovals(store, true, true)
+
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "approved", Reviewer: "carol"})
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "changes_requested", Reviewer: "alice"})
+	approved, _ := store.Get("approvals:" + approvalsPR)
+	assert.Equal(t, "bob carol", approved)
+	changes, _ := store.Get("changes:" + approvalsPR)
+	assert.Equal(t, "alice", changes)
+}
+
+func TestApprovalsSummary(t *testing.T) {
+	a := newApprovals(NewMemoryStore(), false, true)
+
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "approved", Reviewer: "alice"})
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "approved", Reviewer: "bob"})
+	review := a.track(gh.Message{Text: "carol reviewed", PullRequest: approvalsPR, Review: "changes_requested", Reviewer: "carol"})
+	assert.Equal(t, "carol reviewed\nreviews: 2 approved, 1 changes requested", review.Text)
+
+	synchronized := a.track(gh.Message{Text: "pushed", PullRequest: approvalsPR})
+	assert.Equal(t, "pushed\nreviews: 2 approved, 1 changes requested", synchronized.Text)
+
+	a.track(gh.Message{PullRequest: approvalsPR, Review: "dismissed", Reviewer: "alice"})
+	synchronized = a.track(gh.Message{Text: "pushed", PullRequest: approvalsPR})
+	assert.Equal(t, "pushed\nreviews: 1 approved, 1 changes requested", synchronized.Text)
+
+	other := a.track(gh.Message{Text: "opened", PullRequest: "https://github.com/Codertocat/Hello-World/pull/2"})
+	assert.Equal(t, "opened", other.Text)
+}
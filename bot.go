@@ -0,0 +1,474 @@
+package telebot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/berserktech/telebot/gh"
+	"github.com/berserktech/telebot/tg"
+)
+
+// Bot sends the GitHub events it receives to Telegram.
+type Bot struct {
+	config Config
+	// queue sends the messages in the background. It's nil unless the bot
+	// runs in server mode.
+	queue *queue
+	// statuses sums up the statuses of each commit. It's nil unless the bot
+	// runs in server mode with Config.StatusWindow set.
+	statuses *coalescer
+	// breaker stops sending to Telegram while it's failing. It's nil unless
+	// the bot runs in server mode with Config.BreakerFailures set.
+	breaker *breaker
+	// throttle drops the messages of the kinds of events that flood a chat.
+	// It's nil unless the bot runs in server mode with Config.ThrottleLimit
+	// set.
+	throttle *throttle
+	// dedup drops the messages already sent to the same chat a moment ago.
+	// It's nil unless the bot runs in server mode with Config.DedupWindow
+	// set.
+	dedup *dedup
+	// logs collapses the errors logged over and over. It's nil unless the
+	// bot runs in server mode with Config.LogWindow set.
+	logs *logLimiter
+	// approvals counts the approvals of the pull requests, for their merge
+	// messages, and the changes requested with Config.ReviewSummary. It's nil
+	// unless the bot runs in server mode with Config.ApprovalCounts or
+	// Config.ReviewSummary set.
+	approvals *approvals
+	// store keeps the state of the server mode, in Config.StateDir if it's
+	// set, so that it survives restarts.
+	store Store
+	// digest sends the messages of each chat together, once in a while.
+	// It's nil unless the bot runs in server mode with Config.DigestWindow
+	// set.
+	digest *digest
+	// mute is set through the /mute and /unmute Telegram commands.
+	mute *tg.Mute
+	// newClient returns the client used to reach Telegram. It's replaced by
+	// the tests.
+	newClient func() (tg.TelegramClient, error)
+	// cached is the client made by newClient, once it could be made.
+	cached   tg.TelegramClient
+	cachedMu sync.Mutex
+	// notifiers are sent every message too, as set by Config.RelayURL and
+	// Config.TeamsURL.
+	notifiers []Notifier
+	// rules are the Config.RouteRules, ready to be matched.
+	rules []routeRule
+}
+
+// NewBot returns a bot that sends each message before answering to GitHub.
+// That's what Zeit needs, since nothing runs once the response is written.
+func NewBot(config Config) *Bot {
+	b := &Bot{config: config, mute: &tg.Mute{}, rules: compileRules(config.RouteRules)}
+	b.newClient = b.telegram
+	if b.config.NotifierTimeout == 0 {
+		b.config.NotifierTimeout = DefaultNotifierTimeout
+	}
+	if config.ShortenLinks != "" && b.config.GitHub.Shortener == nil {
+		b.config.GitHub.Shortener = NewLinkShortener(config.ShortenLinks)
+	}
+	if config.RelayURL != "" {
+		relay := NewWebhookNotifier(config.RelayURL)
+		relay.Client.Timeout = b.config.NotifierTimeout
+		b.notifiers = append(b.notifiers, relay)
+	}
+	if config.TeamsURL != "" {
+		teams := NewTeamsNotifier(config.TeamsURL)
+		teams.Client.Timeout = b.config.NotifierTimeout
+		b.notifiers = append(b.notifiers, teams)
+	}
+	return b
+}
+
+// NewServerBot returns a bot for long running servers. It answers to GitHub
+// right away and sends the messages in the background, keeping the order of
+// the messages of each chat.
+func NewServerBot(config Config) *Bot {
+	b := NewBot(config)
+	b.queue = newQueue(config.SendWorkers)
+	if config.BreakerFailures > 0 {
+		b.breaker = newBreaker(config.BreakerFailures, config.BreakerCooldown)
+	}
+	b.store = newStore(config.StateDir)
+	if config.DedupWindow > 0 {
+		b.dedup = newDedup(config.DedupWindow, b.store)
+	}
+	if config.LogWindow > 0 {
+		b.logs = newLogLimiter(config.LogWindow)
+	}
+	if config.ApprovalCounts || config.ReviewSummary {
+		b.approvals = newApprovals(b.store, config.ApprovalCounts, config.ReviewSummary)
+	}
+	if config.ThrottleLimit > 0 {
+		b.throttle = newThrottle(config.ThrottleLimit, config.ThrottleWindow, b.sendSuppressed)
+	}
+	if config.StatusWindow > 0 {
+		b.statuses = newCoalescer(config.StatusWindow, config.StatusFlushOnFailure, b.sendStatuses)
+	}
+	if config.DigestWindow > 0 {
+		b.digest = newDigest(config.DigestWindow, b.sendDigest)
+	}
+	return b
+}
+
+// newStore returns a store in the directory, or in memory if there's no
+// directory or it can't be used.
+func newStore(dir string) Store {
+	if dir == "" {
+		return NewMemoryStore()
+	}
+	store, err := NewFileStore(dir)
+	if err != nil {
+		log.Printf("telebot: can't keep the state in %s, keeping it in memory: %s", dir, err)
+		return NewMemoryStore()
+	}
+	return store
+}
+
+// ListenCommands answers the /mute and /unmute commands sent through
+// Telegram by the administrators of the chats the bot sends to: the ones of
+// the config, and the given ones. The commands of any other chat are ignored,
+// since the mute applies to every chat. It blocks for as long as the bot runs,
+// so it's only useful in server mode.
+func (b *Bot) ListenCommands(chatIds ...string) error {
+	// The updates are long polled, so they're never timed out.
+	client, err := tg.NewBot(b.config.Token, b.config.Proxy)
+	if err != nil {
+		return err
+	}
+	return tg.ListenCommands(client, b.mute, b.commandChats(chatIds...))
+}
+
+// commandChats returns the chats of the config, on top of the given ones. The
+// ones that aren't set are empty, and they match no chat.
+func (b *Bot) commandChats(chatIds ...string) []string {
+	chats := []string{
+		b.config.ChatID,
+		b.config.SecurityChatID,
+		b.config.PackagesChatID,
+		b.config.ComplianceChatID,
+		b.config.InfraChatID,
+	}
+	for _, chatId := range b.config.Routes {
+		chats = append(chats, chatId)
+	}
+	for _, rule := range b.config.RouteRules {
+		chats = append(chats, rule.ChatID)
+	}
+	return append(chats, chatIds...)
+}
+
+// SelfTestMessage is the message sent by SelfTest.
+const SelfTestMessage = "bot online"
+
+// SelfTest sends the SelfTestMessage to the configured chat, right away, to
+// check that the token, the chat ID and the network are fine.
+func (b *Bot) SelfTest() error {
+	return b.sendNow(gh.Message{Text: SelfTestMessage}, b.config.ChatID)
+}
+
+// StartupMessage is the message sent by Startup, with the repositories being
+// watched.
+const StartupMessage = "BerserkTech bot is now watching %s"
+
+// startupTTL is how long the chats are remembered to have gotten the
+// StartupMessage. It's only sent again once it's over.
+const startupTTL = 10 * 365 * 24 * time.Hour
+
+// Startup queues the StartupMessage to each one of the chats that never got it,
+// as far as the store remembers. It doesn't wait for it to be sent, and the
+// chats that fail to get it are only logged, so they get it on the next start,
+// as do the ones that are muted by then. It only goes to Telegram, without the
+// notifiers nor the footer, so nothing is sent with Config.RelayOnly.
+func (b *Bot) Startup(chatIds ...string) {
+	if b.queue == nil || b.config.RelayOnly {
+		return
+	}
+	if b.store == nil {
+		b.store = NewMemoryStore()
+	}
+
+	message := gh.Message{Text: fmt.Sprintf(StartupMessage, b.watching())}
+	seen := map[string]bool{}
+	for _, chatId := range chatIds {
+		key := "startup:" + chatId
+		if _, ok := b.store.Get(key); ok || seen[chatId] || chatId == "" {
+			continue
+		}
+		seen[chatId] = true
+
+		chatId := chatId
+		b.queue.push(chatId, func() {
+			if b.mute.Muted() {
+				log.Printf("telebot: muted, not sending the startup message to %s", chatId)
+				return
+			}
+			if err := b.sendTelegram(message, chatId); err != nil {
+				log.Printf("telebot: can't send the startup message to %s, %s", chatId, err)
+				return
+			}
+			if err := b.store.Set(key, "", startupTTL); err != nil {
+				log.Print(err)
+			}
+		})
+	}
+}
+
+// watching returns the repositories with templates of their own, which are the
+// only ones the bot knows about before their events come, or "GitHub".
+func (b *Bot) watching() string {
+	var repos []string
+	for repo := range b.config.GitHub.RepoTemplates {
+		repos = append(repos, "`"+repo+"`")
+	}
+	if len(repos) == 0 {
+		return "GitHub"
+	}
+	sort.Strings(repos)
+	return strings.Join(repos, ", ")
+}
+
+// chatFor returns the chat the message goes to, given the chat of the handler
+// that received it. It's empty if the message matches no route rule and
+// Config.RouteFallback is RouteFallbackDrop.
+func (b *Bot) chatFor(message gh.Message, chatId string) string {
+	if message.Security() && b.config.SecurityChatID != "" {
+		return b.config.SecurityChatID
+	}
+	if message.Package() && b.config.PackagesChatID != "" {
+		return b.config.PackagesChatID
+	}
+	if message.Compliance() && b.config.ComplianceChatID != "" {
+		return b.config.ComplianceChatID
+	}
+	if message.Infra() && b.config.InfraChatID != "" {
+		return b.config.InfraChatID
+	}
+	if routed, ok := routeFor(b.rules, message.Repository); ok {
+		return routed
+	}
+	if len(b.rules) > 0 && b.config.RouteFallback == RouteFallbackDrop {
+		return ""
+	}
+	return chatId
+}
+
+// ping sends the message to the private Telegram chat of the user it was
+// assigned to, as set by Config.UserMap, on top of the chat it goes to.
+func (b *Bot) ping(message gh.Message, chatId string) {
+	userChat := b.config.UserMap[message.Assignee]
+	if message.Assignee == "" || userChat == "" || userChat == chatId || b.config.RelayOnly {
+		return
+	}
+	userChat = tg.UserChat(userChat)
+
+	send := func() {
+		if b.mute.Muted() {
+			return
+		}
+		if err := b.sendTelegram(message, userChat); err != nil {
+			b.logError(err)
+		}
+	}
+	if b.queue != nil {
+		b.queue.push(userChat, send)
+		return
+	}
+	send()
+}
+
+// telegram returns a client for the Telegram Bot API.
+func (b *Bot) telegram() (tg.TelegramClient, error) {
+	return tg.NewBotWithTimeout(b.config.Token, b.config.Proxy, b.config.NotifierTimeout)
+}
+
+// send sends the message to the given chat, and to the notifiers, unless the
+// notifications are muted. The errors of each one of them are returned
+// together.
+func (b *Bot) send(message gh.Message, chatId string) error {
+	// Telegram rejects the messages without text.
+	if blank(message.Text) {
+		return nil
+	}
+	if b.mute.Muted() {
+		log.Printf("telebot: muted, not sending to %s", chatId)
+		return nil
+	}
+
+	var errs notifyErrors
+	if !b.config.RelayOnly {
+		if err := b.sendTelegram(message, chatId); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, n := range b.notifiers {
+		if err := b.notify(n, message, chatId); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs.err()
+}
+
+// notify sends the message to the notifier, giving up once the
+// Config.NotifierTimeout is over if it's a ContextNotifier. The others are
+// waited for as long as they take.
+func (b *Bot) notify(n Notifier, message gh.Message, chatId string) error {
+	cn, ok := n.(ContextNotifier)
+	if !ok {
+		return n.Notify(message, chatId)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.NotifierTimeout)
+	defer cancel()
+	return cn.NotifyContext(ctx, message, chatId)
+}
+
+// sendTelegram sends the message to the given chat, unless the breaker is
+// open. Messages with an image are sent as a photo with a caption.
+func (b *Bot) sendTelegram(message gh.Message, chatId string) error {
+	if b.breaker == nil {
+		return b.sendNow(message, chatId)
+	}
+	if !b.breaker.allow() {
+		return ErrBreakerOpen
+	}
+	err := b.sendNow(message, chatId)
+	b.breaker.done(err)
+	return err
+}
+
+// client returns the client used to reach Telegram, made on first use. If it
+// can't be made, as with a bad token or while Telegram can't be reached, the
+// error is returned and it's made once again on the next use, so that only a
+// working one is kept.
+func (b *Bot) client() (tg.TelegramClient, error) {
+	b.cachedMu.Lock()
+	defer b.cachedMu.Unlock()
+
+	if b.cached != nil {
+		return b.cached, nil
+	}
+	client, err := b.newClient()
+	if err != nil {
+		return nil, err
+	}
+	b.cached = client
+	return client, nil
+}
+
+// sendNow sends the message to Telegram.
+func (b *Bot) sendNow(message gh.Message, chatId string) error {
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+	options := b.sendOptions(message, chatId)
+	text := b.withFooter(message)
+	if message.ImageURL != "" {
+		return tg.SendPhotoWith(client, message.ImageURL, text, chatId, options)
+	}
+	return tg.SendMessageWith(client, text, chatId, options)
+}
+
+// sendOptions returns how the message is sent, given its kind of event and the
+// chat it's sent to. The failures are never silent.
+func (b *Bot) sendOptions(message gh.Message, chatId string) tg.Options {
+	options := tg.Options{
+		Preview:      b.config.PreviewKinds[message.Event],
+		Silent:       b.config.SilentEvents && !message.Failed,
+		MarkdownOnly: b.config.MarkdownOnly,
+		Split:        b.config.LongMessage == "split",
+		ParseMode:    b.config.ParseModes[chatId],
+	}
+	for _, button := range message.Buttons {
+		options.Buttons = append(options.Buttons, tg.Button{Text: button.Text, URL: button.URL})
+	}
+	return options
+}
+
+// sendStatuses queues a single message summing up the statuses of a commit.
+// It's about the repository and the commit of the statuses.
+func (b *Bot) sendStatuses(chatId string, statuses []gh.Status) {
+	message := gh.Message{Text: gh.FormatStatuses(statuses, b.config.GitHub), Event: "status"}
+	for _, status := range statuses {
+		message.Failed = message.Failed || status.Failed()
+		if message.Repository == "" {
+			message.Repository = status.Repository
+		}
+		if message.URL == "" {
+			message.URL = status.HTMLURL
+		}
+	}
+	b.enqueue(message, chatId, "")
+}
+
+// sendSuppressed queues a single message saying how many messages the throttle
+// dropped.
+func (b *Bot) sendSuppressed(chatId string, repo string, event string, count int) {
+	text := fmt.Sprintf("%d more `%s` events suppressed", count, event)
+	if repo != "" {
+		text += fmt.Sprintf(" in `%s`", repo)
+	}
+	b.enqueue(gh.Message{Text: text, Event: event, Repository: repo}, chatId, "")
+}
+
+// sendDigest queues the digest of the messages of a chat, split in as many
+// messages as needed.
+func (b *Bot) sendDigest(chatId string, messages []gh.Message) {
+	var failed bool
+	for _, message := range messages {
+		failed = failed || message.Failed
+	}
+	for _, text := range formatDigest(messages) {
+		b.push(gh.Message{Text: text, Event: "digest", Failed: failed}, chatId, "")
+	}
+}
+
+// enqueue sends the message in the background, holding it for the digest if
+// there's one. It returns true if it was held. The text it's deduplicated by,
+// if it's not empty, is remembered once it's sent or held.
+func (b *Bot) enqueue(message gh.Message, chatId string, dedupText string) bool {
+	if b.digest != nil && digested(message) {
+		b.digest.add(chatId, message)
+		b.remember(chatId, dedupText)
+		return true
+	}
+	b.push(message, chatId, dedupText)
+	return false
+}
+
+// push adds the message to the queue of its chat. The text it's deduplicated
+// by, if it's not empty, is checked once again before it's sent, since the
+// same event can be queued twice before it's sent once, and it's remembered
+// once it's sent.
+func (b *Bot) push(message gh.Message, chatId string, dedupText string) {
+	b.queue.push(chatId, func() {
+		if dedupText != "" && b.dedup != nil && b.dedup.duplicate(chatId, dedupText) {
+			return
+		}
+		if err := b.send(message, chatId); err != nil {
+			b.logError(err)
+			return
+		}
+		b.remember(chatId, dedupText)
+	})
+}
+
+// remember records the text the message sent to the chat is deduplicated by,
+// unless it's empty or there's no dedup.
+func (b *Bot) remember(chatId string, dedupText string) {
+	if dedupText != "" && b.dedup != nil {
+		b.dedup.record(chatId, dedupText)
+	}
+}
+
+// blank returns true if the text has nothing but whitespace.
+func blank(text string) bool {
+	return strings.TrimSpace(text) == ""
+}
@@ -0,0 +1,318 @@
+package telebot
+
+import (
+	"errors"
+	"github.com/berserktech/telebot/gh"
+	"github.com/berserktech/telebot/tg"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockClient records the messages it's asked to send, instead of sending them.
+type mockClient struct {
+	sent []tgbotapi.Chattable
+}
+
+func (m *mockClient) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	m.sent = append(m.sent, c)
+	return tgbotapi.Message{}, nil
+}
+
+// mockBot returns a bot that sends its messages to the returned client.
+func mockBot(config Config) (*Bot, *mockClient) {
+	client := &mockClient{}
+	bot := NewBot(config)
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+	return bot, client
+}
+
+func TestSend(t *testing.T) {
+	bot, client := mockBot(Config{})
+
+	err := bot.send(gh.Message{Text: "hello"}, "123")
+	assert.Nil(t, err)
+	assert.Len(t, client.sent, 1)
+}
+
+func TestSendPreviewKinds(t *testing.T) {
+	bot, client := mockBot(Config{PreviewKinds: map[string]bool{"release": true}})
+
+	assert.Nil(t, bot.send(gh.Message{Text: "hello", Event: "release"}, "123"))
+	assert.Nil(t, bot.send(gh.Message{Text: "hello", Event: "issue_comment"}, "123"))
+
+	assert.False(t, client.sent[0].(tgbotapi.MessageConfig).DisableWebPagePreview)
+	assert.True(t, client.sent[1].(tgbotapi.MessageConfig).DisableWebPagePreview)
+}
+
+func TestSendSilentEvents(t *testing.T) {
+	bot, client := mockBot(Config{SilentEvents: true})
+
+	assert.Nil(t, bot.send(gh.Message{Text: "opened", Event: "issues"}, "123"))
+	assert.Nil(t, bot.send(gh.Message{Text: "failed", Event: "status", Failed: true}, "123"))
+
+	assert.True(t, client.sent[0].(tgbotapi.MessageConfig).DisableNotification)
+	assert.False(t, client.sent[1].(tgbotapi.MessageConfig).DisableNotification)
+}
+
+func TestSendButtons(t *testing.T) {
+	bot, client := mockBot(Config{})
+	buttons := []gh.Button{{Text: "Open PR", URL: "https://github.com/Codertocat/Hello-World/pull/1"}}
+
+	assert.Nil(t, bot.send(gh.Message{Text: "opened", Event: "pull_request", Buttons: buttons}, "123"))
+
+	keyboard := client.sent[0].(tgbotapi.MessageConfig).ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	assert.Equal(t, "Open PR", keyboard.InlineKeyboard[0][0].Text)
+}
+
+func TestSendParseModes(t *testing.T) {
+	bot, client := mockBot(Config{ParseModes: map[string]string{"123": "HTML", "456": "MarkdownV2"}})
+
+	assert.Nil(t, bot.send(gh.Message{Text: "*octocat* opened PR #1"}, "123"))
+	assert.Nil(t, bot.send(gh.Message{Text: "*octocat* opened PR #1"}, "456"))
+
+	html := client.sent[0].(tgbotapi.MessageConfig)
+	assert.Equal(t, "HTML", html.ParseMode)
+	assert.Equal(t, "<b>octocat</b> opened PR #1", html.Text)
+	markdownV2 := client.sent[1].(tgbotapi.MessageConfig)
+	assert.Equal(t, "MarkdownV2", markdownV2.ParseMode)
+	assert.Equal(t, "*octocat* opened PR \\#1", markdownV2.Text)
+}
+
+func TestSendFooter(t *testing.T) {
+	bot, client := mockBot(Config{Footer: "via BerserkTech bot · {repo} · {event}"})
+
+	assert.Nil(t, bot.send(gh.Message{Text: "opened", Event: "pull_request", Repository: "Codertocat/Hello_World"}, "123"))
+	assert.Nil(t, bot.send(gh.Message{Text: SelfTestMessage}, "123"))
+
+	assert.Equal(t, "opened\nvia BerserkTech bot · Codertocat/Hello\\_World · pull\\_request", client.sent[0].(tgbotapi.MessageConfig).Text)
+	assert.Equal(t, SelfTestMessage, client.sent[1].(tgbotapi.MessageConfig).Text)
+}
+
+func TestSendFooterTooLong(t *testing.T) {
+	bot, client := mockBot(Config{Footer: "via BerserkTech bot"})
+	text := strings.Repeat("a", tg.MaxMessageLength-5)
+
+	assert.Nil(t, bot.send(gh.Message{Text: text, Event: "issues"}, "123"))
+
+	assert.Equal(t, text, client.sent[0].(tgbotapi.MessageConfig).Text)
+}
+
+func TestSendBreakerOpen(t *testing.T) {
+	bot, client := mockBot(Config{})
+	bot.breaker = newBreaker(1, time.Minute)
+	bot.breaker.allow()
+	bot.breaker.done(errors.New("telegram is down"))
+
+	assert.Equal(t, ErrBreakerOpen, bot.send(gh.Message{Text: "hello"}, "123"))
+	assert.Len(t, client.sent, 0)
+}
+
+func TestSelfTest(t *testing.T) {
+	bot, client := mockBot(Config{ChatID: "123"})
+
+	assert.Nil(t, bot.SelfTest())
+	assert.Len(t, client.sent, 1)
+	assert.Equal(t, SelfTestMessage, client.sent[0].(tgbotapi.MessageConfig).Text)
+}
+
+func TestCommandChats(t *testing.T) {
+	bot := NewBot(Config{
+		ChatID:         "123",
+		SecurityChatID: "999",
+		Routes:         map[string]string{"/team-a": "111"},
+		RouteRules:     []RouteRule{{Pattern: "^acme/", ChatID: "@acme"}},
+	})
+
+	chats := bot.commandChats("222")
+	for _, chatId := range []string{"123", "999", "111", "@acme", "222"} {
+		assert.Contains(t, chats, chatId)
+	}
+	assert.NotContains(t, chats, "456")
+}
+
+func TestSelfTestFailure(t *testing.T) {
+	bot := NewBot(Config{ChatID: "123"})
+	bot.newClient = func() (tg.TelegramClient, error) { return nil, errors.New("bad token") }
+
+	assert.EqualError(t, bot.SelfTest(), "bad token")
+}
+
+func TestClientRetried(t *testing.T) {
+	bot := NewBot(Config{})
+	client := &mockClient{}
+	made := 0
+	bot.newClient = func() (tg.TelegramClient, error) {
+		made++
+		if made == 1 {
+			return nil, errors.New("bad token")
+		}
+		return client, nil
+	}
+
+	// The failure isn't kept, the client is made once again.
+	assert.EqualError(t, bot.send(gh.Message{Text: "hello"}, "123"), "bad token")
+	assert.Nil(t, bot.send(gh.Message{Text: "hello"}, "123"))
+	// And then it's kept.
+	assert.Nil(t, bot.send(gh.Message{Text: "hello"}, "123"))
+	assert.Equal(t, 2, made)
+	assert.Len(t, client.sent, 2)
+}
+
+func TestSendBlank(t *testing.T) {
+	bot, client := mockBot(Config{})
+
+	err := bot.send(gh.Message{Text: " \n\t "}, "123")
+	assert.Nil(t, err)
+	assert.Len(t, client.sent, 0)
+}
+
+func TestChatForSecurity(t *testing.T) {
+	bot := NewBot(Config{SecurityChatID: "999"})
+
+	assert.Equal(t, "999", bot.chatFor(gh.Message{Event: "security_advisory"}, "123"))
+	assert.Equal(t, "123", bot.chatFor(gh.Message{Event: "issues"}, "123"))
+}
+
+func TestChatForSecurityNotConfigured(t *testing.T) {
+	bot := NewBot(Config{})
+
+	assert.Equal(t, "123", bot.chatFor(gh.Message{Event: "repository_vulnerability_alert"}, "123"))
+}
+
+func TestChatForPackages(t *testing.T) {
+	bot := NewBot(Config{PackagesChatID: "777"})
+
+	assert.Equal(t, "777", bot.chatFor(gh.Message{Event: "registry_package"}, "123"))
+	assert.Equal(t, "123", bot.chatFor(gh.Message{Event: "security_advisory"}, "123"))
+}
+
+func TestChatForCompliance(t *testing.T) {
+	bot := NewBot(Config{ComplianceChatID: "555"})
+
+	assert.Equal(t, "555", bot.chatFor(gh.Message{Event: "branch_protection_rule"}, "123"))
+	assert.Equal(t, "123", bot.chatFor(gh.Message{Event: "issues"}, "123"))
+}
+
+func TestChatForInfra(t *testing.T) {
+	bot := NewBot(Config{InfraChatID: "333"})
+
+	assert.Equal(t, "333", bot.chatFor(gh.Message{Event: "workflow_job"}, "123"))
+	assert.Equal(t, "123", bot.chatFor(gh.Message{Event: "status"}, "123"))
+}
+
+func TestChatForRouteRules(t *testing.T) {
+	bot := NewBot(Config{SecurityChatID: "999", RouteRules: []RouteRule{
+		{Pattern: "^acme/team-a-api$", ChatID: "111"},
+		{Pattern: "^acme/team-a-", ChatID: "222"},
+		{Pattern: "(invalid", ChatID: "333"},
+	}})
+
+	// The first rule that matches wins.
+	assert.Equal(t, "111", bot.chatFor(gh.Message{Event: "issues", Repository: "acme/team-a-api"}, "123"))
+	assert.Equal(t, "222", bot.chatFor(gh.Message{Event: "issues", Repository: "acme/team-a-web"}, "123"))
+	// The rest go to the chat of the handler.
+	assert.Equal(t, "123", bot.chatFor(gh.Message{Event: "issues", Repository: "acme/team-b-web"}, "123"))
+	assert.Equal(t, "123", bot.chatFor(gh.Message{Event: "issues"}, "123"))
+	// The security alerts still go to their own chat.
+	assert.Equal(t, "999", bot.chatFor(gh.Message{Event: "security_advisory", Repository: "acme/team-a-api"}, "123"))
+}
+
+func TestChatForRouteFallback(t *testing.T) {
+	rules := []RouteRule{{Pattern: "^acme/team-a-", ChatID: "111"}}
+
+	bot := NewBot(Config{RouteRules: rules, RouteFallback: RouteFallbackDefault})
+	assert.Equal(t, "123", bot.chatFor(gh.Message{Event: "issues", Repository: "acme/team-b-web"}, "123"))
+
+	bot = NewBot(Config{SecurityChatID: "999", RouteRules: rules, RouteFallback: RouteFallbackDrop})
+	assert.Equal(t, "111", bot.chatFor(gh.Message{Event: "issues", Repository: "acme/team-a-api"}, "123"))
+	assert.Equal(t, "", bot.chatFor(gh.Message{Event: "issues", Repository: "acme/team-b-web"}, "123"))
+	assert.Equal(t, "", bot.chatFor(gh.Message{Event: "issues"}, "123"))
+	// The chats of their own still get their messages.
+	assert.Equal(t, "999", bot.chatFor(gh.Message{Event: "security_advisory", Repository: "acme/team-b-web"}, "123"))
+
+	// Without rules, there's nothing to drop.
+	bot = NewBot(Config{RouteFallback: RouteFallbackDrop})
+	assert.Equal(t, "123", bot.chatFor(gh.Message{Event: "issues", Repository: "acme/team-b-web"}, "123"))
+}
+
+func TestPingAssignee(t *testing.T) {
+	bot, client := mockBot(Config{UserMap: map[string]string{"octocat": "456"}})
+
+	bot.ping(gh.Message{Text: "hello", Assignee: "octocat"}, "123")
+	bot.ping(gh.Message{Text: "hello", Assignee: "Codertocat"}, "123")
+	bot.ping(gh.Message{Text: "hello"}, "123")
+
+	// The private chats of the users are positive.
+	assert.Len(t, client.sent, 1)
+	assert.Equal(t, int64(456), client.sent[0].(tgbotapi.MessageConfig).ChatID)
+}
+
+func TestStartup(t *testing.T) {
+	bot := NewServerBot(Config{GitHub: gh.Options{RepoTemplates: map[string]map[string]string{"Codertocat/Hello-World": {}}}})
+	client := &mockClient{}
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+
+	bot.Startup("123", "123")
+	bot.queue.wait()
+	bot.Startup("123")
+	bot.queue.wait()
+
+	assert.Len(t, client.sent, 1)
+	assert.Equal(t, "BerserkTech bot is now watching `Codertocat/Hello-World`", client.sent[0].(tgbotapi.MessageConfig).Text)
+}
+
+func TestStartupOnlyTelegram(t *testing.T) {
+	relayed := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		relayed <- struct{}{}
+	}))
+	defer server.Close()
+
+	bot := NewServerBot(Config{RelayURL: server.URL, Footer: "via BerserkTech bot"})
+	client := &mockClient{}
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+
+	bot.Startup("123")
+	bot.queue.wait()
+
+	assert.Len(t, client.sent, 1)
+	assert.Equal(t, "BerserkTech bot is now watching GitHub", client.sent[0].(tgbotapi.MessageConfig).Text)
+	assert.Len(t, relayed, 0)
+}
+
+func TestStartupMuted(t *testing.T) {
+	bot := NewServerBot(Config{})
+	client := &mockClient{}
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+
+	bot.mute.For(time.Hour)
+	bot.Startup("123")
+	bot.queue.wait()
+	assert.Len(t, client.sent, 0)
+
+	// It's sent on the next start, once it's unmuted.
+	bot.mute.Clear()
+	bot.Startup("123")
+	bot.queue.wait()
+	assert.Len(t, client.sent, 1)
+}
+
+func TestStartupFailing(t *testing.T) {
+	bot := NewServerBot(Config{})
+	bot.newClient = func() (tg.TelegramClient, error) { return nil, errors.New("bad token") }
+
+	bot.Startup("123")
+	bot.queue.wait()
+
+	// It's sent once again on the next start.
+	client := &mockClient{}
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+	bot.Startup("123")
+	bot.queue.wait()
+	assert.Len(t, client.sent, 1)
+}
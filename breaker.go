@@ -0,0 +1,120 @@
+package telebot
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// ErrBreakerOpen is returned instead of sending a message while Telegram is
+// failing.
+var ErrBreakerOpen = errors.New("telebot: too many failures sending to Telegram, not sending for a while")
+
+// The states of the breaker.
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// breaker stops sending messages to Telegram after too many failures in a row,
+// so that it isn't hammered while it's down. Once the cooldown is over, a
+// single message is let through to check if it's back: the breaker closes if
+// it's sent, and opens again if it fails.
+type breaker struct {
+	failures int
+	cooldown time.Duration
+	// now is replaced by the tests.
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failed   int
+	openedAt time.Time
+	trying   bool
+}
+
+func newBreaker(failures int, cooldown time.Duration) *breaker {
+	return &breaker{failures: failures, cooldown: cooldown, now: time.Now, state: breakerClosed}
+}
+
+// allow returns true if a message can be sent. Every allowed message must be
+// followed by a call to done.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.trying = true
+		return true
+	case breakerHalfOpen:
+		if b.trying {
+			return false
+		}
+		b.trying = true
+		return true
+	}
+	return true
+}
+
+// done records the result of sending a message. Only the transient errors are
+// failures: the rest mean that Telegram is up, and that the message is wrong.
+func (b *breaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trying = false
+	if err == nil || !transient(err) {
+		b.state = breakerClosed
+		b.failed = 0
+		return
+	}
+
+	b.failed++
+	if b.state == breakerHalfOpen || b.failed >= b.failures {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// permanentErrors start the descriptions of Telegram's 4xx errors, other than
+// sending too much. They're about the message, so sending it again won't help.
+var permanentErrors = []string{"Bad Request", "Unauthorized", "Forbidden", "Not Found", "Conflict"}
+
+// transient returns true if the error of a send can go away by itself, as the
+// ones of the network, of Telegram being down (its 5xx) or of sending too much
+// (its 429) do. The ones of the message, such as a chat that doesn't exist or
+// Markdown that can't be parsed, aren't.
+func transient(err error) bool {
+	switch e := err.(type) {
+	case tgbotapi.Error:
+		if e.RetryAfter > 0 {
+			return true
+		}
+		for _, prefix := range permanentErrors {
+			if strings.HasPrefix(e.Message, prefix) {
+				return false
+			}
+		}
+	case *strconv.NumError:
+		// The chat ID isn't a number.
+		return false
+	}
+	return true
+}
+
+// State returns "closed", "open" or "half-open".
+func (b *breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
@@ -0,0 +1,82 @@
+package telebot
+
+import (
+	"errors"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	failure := errors.New("telegram is down")
+
+	// Closed: the first failure isn't enough to open it.
+	assert.True(t, b.allow())
+	b.done(failure)
+	assert.Equal(t, breakerClosed, b.State())
+
+	// Open: the messages are skipped until the cooldown is over.
+	assert.True(t, b.allow())
+	b.done(failure)
+	assert.Equal(t, breakerOpen, b.State())
+	assert.False(t, b.allow())
+
+	// Half-open: a single message is let through.
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow())
+	assert.Equal(t, breakerHalfOpen, b.State())
+	assert.False(t, b.allow())
+
+	// Closed again, once it's sent.
+	b.done(nil)
+	assert.Equal(t, breakerClosed, b.State())
+	assert.True(t, b.allow())
+}
+
+func TestBreakerPermanentErrors(t *testing.T) {
+	b := newBreaker(1, time.Minute)
+
+	for _, err := range []error{
+		tgbotapi.Error{Message: "Bad Request: chat not found"},
+		tgbotapi.Error{Message: "Bad Request: can't parse entities: Can't find end of the entity"},
+		tgbotapi.Error{Message: "Forbidden: bot was kicked from the group chat"},
+	} {
+		assert.True(t, b.allow())
+		b.done(err)
+		assert.Equal(t, breakerClosed, b.State(), err.Error())
+	}
+
+	// Sending too much does count.
+	assert.True(t, b.allow())
+	b.done(tgbotapi.Error{Message: "Too Many Requests: retry after 5", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5}})
+	assert.Equal(t, breakerOpen, b.State())
+}
+
+func TestTransient(t *testing.T) {
+	assert.True(t, transient(errors.New("dial tcp: i/o timeout")))
+	assert.True(t, transient(tgbotapi.Error{Message: "Internal Server Error"}))
+	assert.True(t, transient(tgbotapi.Error{Message: "Bad Gateway"}))
+	assert.False(t, transient(tgbotapi.Error{Message: "Unauthorized"}))
+	_, err := strconv.ParseInt("@not-a-chat", 10, 64)
+	assert.False(t, transient(err))
+}
+
+func TestBreakerHalfOpenFailure(t *testing.T) {
+	now := time.Now()
+	b := newBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.allow()
+	b.done(errors.New("telegram is down"))
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow())
+	b.done(errors.New("telegram is still down"))
+
+	assert.Equal(t, breakerOpen, b.State())
+	assert.False(t, b.allow())
+}
@@ -0,0 +1,52 @@
+package telebot
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// channelUsername matches the public usernames of the Telegram chats.
+var channelUsername = regexp.MustCompile(`^@[A-Za-z][A-Za-z0-9_]{4,}$`)
+
+// NormalizeChatID cleans up the chat IDs as they're usually pasted: with
+// spaces around them, a leading "+", or a sign. Since the tg package makes the
+// IDs negative by itself, as every group chat ID is, the sign is dropped with
+// a warning. Channels can be given by their username, as in "@channel". An
+// empty ID is left empty.
+func NormalizeChatID(chatId string) (string, error) {
+	id := strings.TrimSpace(chatId)
+	if id == "" {
+		return "", nil
+	}
+
+	if strings.HasPrefix(id, "@") {
+		if !channelUsername.MatchString(id) {
+			return "", fmt.Errorf("telebot: invalid chat username %q", chatId)
+		}
+		return id, nil
+	}
+
+	id = strings.TrimPrefix(id, "+")
+	if strings.HasPrefix(id, "-") {
+		log.Printf("telebot: chat ID %q shouldn't have a sign, telebot makes it negative by itself", chatId)
+		id = strings.TrimPrefix(id, "-")
+	}
+	if _, err := strconv.ParseUint(id, 10, 63); err != nil {
+		return "", fmt.Errorf("telebot: invalid chat ID %q, expected a number or an @username", chatId)
+	}
+
+	return id, nil
+}
+
+// normalizeChat is NormalizeChatID for the configuration, where the chat IDs
+// that can't be used are logged and left out.
+func normalizeChat(name string, chatId string) string {
+	id, err := NormalizeChatID(chatId)
+	if err != nil {
+		log.Printf("%s (%s)", err, name)
+	}
+	return id
+}
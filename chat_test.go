@@ -0,0 +1,37 @@
+package telebot
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeChatID(t *testing.T) {
+	cases := map[string]string{
+		"123":           "123",
+		"  123\n":       "123",
+		"+123":          "123",
+		"-123":          "123",
+		"-1001234567":   "1001234567",
+		" @berserktech": "@berserktech",
+		"":              "",
+	}
+	for input, expected := range cases {
+		id, err := NormalizeChatID(input)
+		assert.Nil(t, err, input)
+		assert.Equal(t, expected, id, input)
+	}
+}
+
+// Intentional failures:
+
+func TestNormalizeChatIDInvalid(t *testing.T) {
+	_, err := NormalizeChatID("12a3")
+	assert.Equal(t, errors.New(`telebot: invalid chat ID "12a3", expected a number or an @username`), err)
+
+	_, err = NormalizeChatID("--123")
+	assert.NotNil(t, err)
+
+	_, err = NormalizeChatID("@me")
+	assert.Equal(t, errors.New(`telebot: invalid chat username "@me"`), err)
+}
@@ -0,0 +1,199 @@
+// Command telebot runs the bot as a standalone HTTP server, for when it's not
+// deployed on Zeit. It answers to GitHub right away and sends the messages in
+// the background. The messages of each chat are sent in the order they were
+// received.
+//
+// It's configured with the same environment variables as the Zeit deployment
+// (or the CONFIG_FILE, whose Routes are mounted along with the ROUTES), plus:
+//
+//   - PORT: The port to listen to. Defaults to 8080.
+//   - HTTP_PATH: The path where the default handler is mounted. Defaults to "/".
+//   - ROUTES: Comma separated list of path=chatID pairs, each one of them
+//     mounting a handler that sends its messages to the given chat. For example:
+//     "/github/team-a=123,/github/team-b=456".
+//   - CHAT_FROM_PATH: If "true", the handler at HTTP_PATH takes the chat ID
+//     from the last segment of the request's path, as in "/github/123". The
+//     paths without a chat ID there, as in "/github/", are not found.
+//   - SEND_WORKERS: How many messages are sent at a time, to different chats.
+//     Defaults to 1. The messages of each chat are always sent in order, but
+//     with more workers the ones of different chats can overtake each other.
+//   - STATUS_WINDOW: How long to wait for more statuses of the same commit, as
+//     in "30s", to send them as a single message such as "3/3 checks passed".
+//     With STATUS_FLUSH_ON_FAILURE set to "true", they're sent as soon as one
+//     of them fails.
+//   - DIGEST_WINDOW: How long to hold the messages of each chat, as in "1h", to
+//     send them together, grouped by repository.
+//   - DEDUP_WINDOW: How long to remember the messages sent, as in "1m", to
+//     avoid sending the same one twice in a row to a chat.
+//   - LOG_WINDOW: How long to collapse the identical errors logged, as in
+//     "1m". The first one is logged, and the rest are counted in a single
+//     line once the window is over.
+//   - STATE_DIR: The directory where what the server remembers is kept, so
+//     that it survives restarts. It's kept in memory by default.
+//   - BREAKER_FAILURES: How many messages in a row can fail to be sent before
+//     the bot stops sending to Telegram for BREAKER_COOLDOWN (one minute by
+//     default). After that, messages are sent once again if the next one goes
+//     through.
+//   - THROTTLE_LIMIT: How many messages of each kind of event, for each
+//     repository, can be sent to a chat within THROTTLE_WINDOW (one minute by
+//     default). The rest are dropped, and counted in a single message sent
+//     once the window is over.
+//   - METRICS_PATH: The path where the metrics are served, in the Prometheus
+//     text format. They're not served if it's empty.
+//   - GENERATE_SAMPLE: The name of a built-in sample event, as in
+//     "issue_opened", to print its message as configured and exit. Nothing is
+//     sent, and no server is started.
+//   - STARTUP_MESSAGE: If "true", the chats of the handlers that never got it
+//     are sent "BerserkTech bot is now watching" once the server starts, unless
+//     it's muted. With STATE_DIR, it's remembered across restarts. The chats of
+//     CHAT_FROM_PATH aren't known beforehand, so they don't get it.
+//   - SELF_TEST: If "true", the bot sends "bot online" to the TELEGRAM_CHAT_ID
+//     and exits, with a non-zero code if it couldn't. No server is started.
+//   - ADMIN_COMMANDS: If "true", the administrators of the chats can mute the
+//     notifications with "/mute 1h", and unmute them with "/unmute". Only the
+//     commands of the chats the bot is configured to send to are answered,
+//     which leaves out the ones of CHAT_FROM_PATH.
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/berserktech/telebot"
+	"github.com/berserktech/telebot/gh"
+)
+
+func main() {
+	config, err := telebot.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if name := os.Getenv("GENERATE_SAMPLE"); name != "" {
+		message, err := gh.Sample(name, config.GitHub)
+		if gh.KindOf(err) == gh.ErrFiltered {
+			fmt.Println("Filtered:", err)
+			return
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(message.Text)
+		return
+	}
+
+	bot := telebot.NewServerBot(config)
+
+	if os.Getenv("SELF_TEST") == "true" {
+		if err := bot.SelfTest(); err != nil {
+			log.Fatal(err)
+		}
+		log.Print("Self test passed")
+		return
+	}
+
+	mux, chats, err := newServeMux(bot, config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if os.Getenv("STARTUP_MESSAGE") == "true" {
+		bot.Startup(chats...)
+	}
+
+	if os.Getenv("ADMIN_COMMANDS") == "true" {
+		go func() {
+			if err := bot.ListenCommands(chats...); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	log.Printf("Listening on :%s", port)
+	log.Fatal(http.ListenAndServe(":"+port, telebot.Recover(mux)))
+}
+
+// newServeMux registers the handlers at the paths configured through the
+// environment, on top of the routes of the config. It returns the chats the
+// handlers send to, as far as they're known up front.
+func newServeMux(bot *telebot.Bot, config telebot.Config) (*http.ServeMux, []string, error) {
+	mux := http.NewServeMux()
+
+	root := os.Getenv("HTTP_PATH")
+	if root == "" {
+		root = "/"
+	}
+	// The paths taken by the default handler and the metrics can't be routes.
+	taken := map[string]bool{root: true}
+
+	var chats []string
+	if os.Getenv("CHAT_FROM_PATH") == "true" {
+		root = strings.TrimSuffix(root, "/") + "/"
+		mux.Handle(root, bot.ChatFromPath())
+		taken[root] = true
+	} else {
+		mux.Handle(root, bot.Handler(config.ChatID))
+		chats = append(chats, config.ChatID)
+	}
+
+	if metrics := os.Getenv("METRICS_PATH"); metrics != "" {
+		mux.Handle(metrics, bot.Metrics())
+		taken[metrics] = true
+	}
+
+	envRoutes, err := parseRoutes(os.Getenv("ROUTES"))
+	if err != nil {
+		return nil, nil, err
+	}
+	all := map[string]string{}
+	for path, chatId := range config.Routes {
+		all[path] = chatId
+	}
+	for path, chatId := range envRoutes {
+		all[path] = chatId
+	}
+	for path, chatId := range all {
+		if taken[path] {
+			return nil, nil, fmt.Errorf("telebot: route %q is already taken", path)
+		}
+		mux.Handle(path, bot.Handler(chatId))
+		chats = append(chats, chatId)
+	}
+
+	return mux, chats, nil
+}
+
+// parseRoutes reads a comma separated list of path=chatID pairs. The paths
+// must be absolute and clean, without empty, "." or ".." segments.
+func parseRoutes(s string) (map[string]string, error) {
+	routes := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "/") || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("telebot: invalid route %q, expected path=chatID", pair)
+		}
+		if clean := path.Clean(parts[0]); clean != strings.TrimSuffix(parts[0], "/") && clean != parts[0] {
+			return nil, fmt.Errorf("telebot: invalid route %q, its path isn't clean", pair)
+		}
+		chatId, err := telebot.NormalizeChatID(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		routes[parts[0]] = chatId
+	}
+
+	return routes, nil
+}
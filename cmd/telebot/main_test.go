@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/berserktech/telebot"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := parseRoutes(" /github/team-a=123, /github/team-b/=-456,,")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"/github/team-a": "123", "/github/team-b/": "456"}, routes)
+
+	routes, err = parseRoutes("")
+	assert.Nil(t, err)
+	assert.Empty(t, routes)
+}
+
+// Intentional failures:
+
+func TestParseRoutesInvalid(t *testing.T) {
+	for _, s := range []string{
+		"github=123",
+		"/github",
+		"/github=",
+		"/github=not a chat",
+		"/github//team-a=123",
+		"/github/../team-a=123",
+		"/github/./=123",
+	} {
+		_, err := parseRoutes(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestNewServeMuxTakenRoute(t *testing.T) {
+	os.Setenv("METRICS_PATH", "/metrics")
+	os.Setenv("ROUTES", "/metrics=123")
+	defer os.Unsetenv("METRICS_PATH")
+	defer os.Unsetenv("ROUTES")
+
+	_, _, err := newServeMux(telebot.NewBot(telebot.Config{}), telebot.Config{})
+	assert.EqualError(t, err, `telebot: route "/metrics" is already taken`)
+
+	os.Setenv("ROUTES", "/=123")
+	_, _, err = newServeMux(telebot.NewBot(telebot.Config{}), telebot.Config{})
+	assert.EqualError(t, err, `telebot: route "/" is already taken`)
+}
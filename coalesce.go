@@ -0,0 +1,83 @@
+package telebot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/berserktech/telebot/gh"
+)
+
+// coalescer holds the statuses of each commit for a while, so that the checks
+// reported in quick succession are sent as a single message.
+type coalescer struct {
+	window time.Duration
+	// flushOnFailure sends the statuses of the commit right away when one of
+	// them fails.
+	flushOnFailure bool
+	// flush is called with the statuses of a commit once they're ready.
+	flush func(chatId string, statuses []gh.Status)
+
+	mu      sync.Mutex
+	batches map[string]*statusBatch
+}
+
+// statusBatch holds the last status reported by each check of a commit.
+type statusBatch struct {
+	chatId   string
+	statuses []gh.Status
+	timer    *time.Timer
+}
+
+func newCoalescer(window time.Duration, flushOnFailure bool, flush func(string, []gh.Status)) *coalescer {
+	return &coalescer{
+		window:         window,
+		flushOnFailure: flushOnFailure,
+		flush:          flush,
+		batches:        map[string]*statusBatch{},
+	}
+}
+
+// add holds the status until the window of its commit is over. The window
+// starts with the first status of the commit.
+func (c *coalescer) add(chatId string, status gh.Status) {
+	key := chatId + "@" + status.SHA
+
+	c.mu.Lock()
+	batch, ok := c.batches[key]
+	if !ok {
+		batch = &statusBatch{chatId: chatId}
+		c.batches[key] = batch
+		batch.timer = time.AfterFunc(c.window, func() { c.flushBatch(key, batch) })
+	}
+	batch.set(status)
+	c.mu.Unlock()
+
+	if c.flushOnFailure && status.Failed() {
+		c.flushBatch(key, batch)
+	}
+}
+
+// flushBatch sends the statuses of the batch, unless they were sent already.
+func (c *coalescer) flushBatch(key string, batch *statusBatch) {
+	c.mu.Lock()
+	if c.batches[key] != batch {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.batches, key)
+	batch.timer.Stop()
+	c.mu.Unlock()
+
+	c.flush(batch.chatId, batch.statuses)
+}
+
+// set keeps the status, replacing the previous one of the same check.
+func (b *statusBatch) set(status gh.Status) {
+	for i, s := range b.statuses {
+		if s.Context == status.Context {
+			b.statuses[i] = status
+			return
+		}
+	}
+	b.statuses = append(b.statuses, status)
+}
@@ -0,0 +1,67 @@
+package telebot
+
+import (
+	"github.com/berserktech/telebot/gh"
+	"github.com/berserktech/telebot/tg"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flushed is a coalescer callback that hands the statuses over a channel.
+func flushed() (chan []gh.Status, func(string, []gh.Status)) {
+	flushes := make(chan []gh.Status, 10)
+	return flushes, func(chatId string, statuses []gh.Status) { flushes <- statuses }
+}
+
+func TestCoalescerWindow(t *testing.T) {
+	flushes, flush := flushed()
+	c := newCoalescer(20*time.Millisecond, false, flush)
+
+	c.add("123", gh.Status{SHA: "abc", Context: "lint", State: "success"})
+	c.add("123", gh.Status{SHA: "abc", Context: "test", State: "failure"})
+	c.add("123", gh.Status{SHA: "abc", Context: "test", State: "success"})
+	c.add("123", gh.Status{SHA: "def", Context: "lint", State: "success"})
+
+	first, second := <-flushes, <-flushes
+	if first[0].SHA != "abc" {
+		first, second = second, first
+	}
+	assert.Equal(t, []gh.Status{
+		{SHA: "abc", Context: "lint", State: "success"},
+		{SHA: "abc", Context: "test", State: "success"},
+	}, first)
+	assert.Len(t, second, 1)
+}
+
+func TestCoalescerFlushOnFailure(t *testing.T) {
+	flushes, flush := flushed()
+	c := newCoalescer(time.Hour, true, flush)
+
+	c.add("123", gh.Status{SHA: "abc", Context: "lint", State: "success"})
+	c.add("123", gh.Status{SHA: "abc", Context: "test", State: "failure"})
+
+	select {
+	case statuses := <-flushes:
+		assert.Len(t, statuses, 2)
+	default:
+		t.Fatal("the failure wasn't sent right away")
+	}
+}
+
+func TestSendStatusesRepository(t *testing.T) {
+	client := &mockClient{}
+	bot := NewServerBot(Config{Footer: "{repo}"})
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+
+	bot.sendStatuses("123", []gh.Status{
+		{SHA: "abc", Context: "lint", State: "success", Repository: "Codertocat/Hello-World", HTMLURL: "https://github.com/Codertocat/Hello-World/commit/abc"},
+		{SHA: "abc", Context: "test", State: "success", Repository: "Codertocat/Hello-World", HTMLURL: "https://github.com/Codertocat/Hello-World/commit/abc"},
+	})
+	bot.queue.wait()
+
+	assert.Len(t, client.sent, 1)
+	assert.True(t, strings.HasSuffix(client.sent[0].(tgbotapi.MessageConfig).Text, "\nCodertocat/Hello-World"))
+}
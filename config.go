@@ -0,0 +1,558 @@
+package telebot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/berserktech/telebot/gh"
+)
+
+// Config holds the settings of the bot.
+type Config struct {
+	// GitHub configures how the GitHub events are parsed.
+	GitHub gh.Options
+	// Token is the Telegram HTTP API token.
+	Token string
+	// ChatID is the chat the messages are sent to, unless they're routed
+	// somewhere else.
+	ChatID string
+	// Proxy is the URL of the proxy used to reach Telegram, if any.
+	Proxy string
+	// SecurityChatID is the chat the security alerts are sent to, instead
+	// of the chat of the handler.
+	SecurityChatID string
+	// PackagesChatID is the chat the GitHub Packages messages are sent to,
+	// instead of the chat of the handler.
+	PackagesChatID string
+	// ComplianceChatID is the chat the changes to the rules of the
+	// repositories are sent to, instead of the chat of the handler.
+	ComplianceChatID string
+	// InfraChatID is the chat the jobs of the workflows are sent to, along
+	// with the runners they run on, instead of the chat of the handler.
+	InfraChatID string
+	// PreviewKinds maps the kinds of events, as in "release", to whether
+	// the preview of their first link is shown. It's hidden by default.
+	PreviewKinds map[string]bool
+	// SilentEvents sends the messages without a notification sound, except
+	// for the failures.
+	SilentEvents bool
+	// MarkdownOnly drops the messages whose Markdown Telegram can't parse,
+	// instead of sending them once again as plain text.
+	MarkdownOnly bool
+	// LongMessage set to "split" sends the messages longer than Telegram
+	// accepts in as many numbered parts as needed.
+	LongMessage string
+	// ParseModes maps the chats to the parse mode their messages are sent
+	// with: "Markdown" (the default), "MarkdownV2" or "HTML". The messages
+	// are written in Markdown and converted for each chat.
+	ParseModes map[string]string
+	// ApprovalCounts says how many reviewers approved the pull requests in
+	// their merge messages. They're counted by the standalone server out of
+	// the reviews it receives, since the payloads don't have them.
+	ApprovalCounts bool
+	// ReviewSummary sums up the reviews of the pull requests in their
+	// messages, as in "reviews: 2 approved, 1 changes requested". They're
+	// kept by the standalone server out of the reviews it receives.
+	ReviewSummary bool
+	// Footer is appended to every message sent to Telegram, as in "via
+	// BerserkTech bot · {repo}". Its {repo}, {event} and {sender} are
+	// replaced by the ones of the message.
+	Footer string
+	// SendWorkers is how many messages the standalone server sends at a
+	// time, to different chats. The messages of each chat are always sent
+	// one at a time, in order. It's one if it's zero.
+	SendWorkers int
+	// StatusWindow is how long the standalone server waits for more statuses
+	// of the same commit, to send them as a single message. They're sent one
+	// by one if it's zero.
+	StatusWindow time.Duration
+	// StatusFlushOnFailure sends the statuses of a commit as soon as one of
+	// them fails, without waiting for the StatusWindow to end.
+	StatusFlushOnFailure bool
+	// DigestWindow is how long the standalone server holds the messages of
+	// each chat, to send them together, grouped by repository. The security
+	// and compliance alerts are always sent right away. Each message is sent
+	// on its own if it's zero.
+	DigestWindow time.Duration
+	// DedupWindow is how long the standalone server remembers the messages
+	// it sends, to avoid sending the same one twice to a chat. Every message
+	// is sent if it's zero.
+	DedupWindow time.Duration
+	// LogWindow is how long the standalone server collapses the identical
+	// errors it logs. The first one is logged, and the rest are counted in
+	// a single line once the window is over. They're all logged if it's
+	// zero.
+	LogWindow time.Duration
+	// StateDir is where the standalone server keeps its state, such as the
+	// messages the dedup remembers, so that it survives restarts. It's kept
+	// in memory if it's empty.
+	StateDir string
+	// BreakerFailures is how many messages in a row the standalone server
+	// fails to send before it stops sending to Telegram for the
+	// BreakerCooldown. There's no breaker if it's zero.
+	BreakerFailures int
+	BreakerCooldown time.Duration
+	// ThrottleLimit is how many messages of each kind of event, for each
+	// repository, the standalone server sends to a chat within the
+	// ThrottleWindow. The rest are summed up in a single message once the
+	// window is over. There's no limit if it's zero.
+	ThrottleLimit  int
+	ThrottleWindow time.Duration
+	// SuccessStatus is the status code of the response once the message is
+	// sent (or queued). It's a 200 if it's zero.
+	SuccessStatus int
+	// SuccessBody is the body of the response once the message is sent (or
+	// queued). Its {result} ("Sent", "Queued" or "Batched"), {message}, {event} and
+	// {chat} placeholders are replaced. It's the result and the message if
+	// it's empty.
+	SuccessBody string
+	// FilteredBody is the body of the response to the events dropped on
+	// purpose, with a {reason} placeholder. It's DefaultFilteredBody if
+	// it's empty, and there's no body if it's "none".
+	FilteredBody string
+	// RelayURL is an HTTP endpoint the messages are POSTed to as JSON, on
+	// top of being sent to Telegram, unless RelayOnly is set.
+	RelayURL  string
+	RelayOnly bool
+	// TeamsURL is the URL of a Microsoft Teams incoming webhook the messages
+	// are posted to as cards, on top of being sent to Telegram, unless
+	// RelayOnly is set.
+	TeamsURL string
+	// ShortenLinks is the endpoint of the LinkShortener the links to GitHub
+	// are shortened through, with a {url} placeholder. They're left as
+	// they are if it's empty.
+	ShortenLinks string
+	// UserMap maps GitHub logins to the private Telegram chats where they're
+	// pinged when a pull request is assigned to them. Their IDs are the ones
+	// of the users, which are positive, and they're sent to as they are
+	// (see tg.UserChat).
+	UserMap map[string]string
+	// NotifierTimeout is how long Telegram and each one of the notifiers are
+	// waited for, so that a slow one can't hang the bot. It's
+	// DefaultNotifierTimeout if it's zero.
+	NotifierTimeout time.Duration
+	// MaxBodyBytes is the biggest request body accepted, in bytes.
+	MaxBodyBytes int64
+	// Routes maps the paths of the standalone server to the chats their
+	// messages are sent to. They're only read from the config file, the
+	// ROUTES environment variable is read by cmd/telebot.
+	Routes map[string]string
+	// RouteRules send the messages of the repositories matching them to
+	// their chats. The first one that matches wins, and the messages of the
+	// repositories that match none go to the chat of the handler, unless
+	// RouteFallback says otherwise.
+	RouteRules []RouteRule
+	// RouteFallback is how the messages of the repositories that match no
+	// RouteRules are handled: RouteFallbackDefault (the default) sends them
+	// to the chat of the handler, and RouteFallbackDrop drops them.
+	RouteFallback string
+}
+
+// DefaultMaxBodyBytes is big enough for the biggest pushes.
+const DefaultMaxBodyBytes = 5 << 20
+
+// DefaultBreakerCooldown is how long the breaker stays open by default.
+const DefaultBreakerCooldown = time.Minute
+
+// DefaultThrottleWindow is how long the throttle counts the messages for, by
+// default.
+const DefaultThrottleWindow = time.Minute
+
+// ConfigFromEnv reads the configuration from the environment variables. If
+// CONFIG_FILE is set, the configuration is read from that file first, and the
+// environment variables that are set override it, even when they're empty or
+// false. A CONFIG_FILE that can't be read is an error, since the bot would run
+// without its settings, such as the secret of the signatures.
+func ConfigFromEnv() (Config, error) {
+	var config Config
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		file, err := ConfigFromFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		config = file
+	}
+
+	for name, set := range envFields(&config) {
+		if _, ok := os.LookupEnv(name); ok {
+			set(name)
+		}
+	}
+	if config.MaxBodyBytes == 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if config.BreakerCooldown == 0 {
+		config.BreakerCooldown = DefaultBreakerCooldown
+	}
+	if config.ThrottleWindow == 0 {
+		config.ThrottleWindow = DefaultThrottleWindow
+	}
+
+	config.ChatID = normalizeChat("TELEGRAM_CHAT_ID", config.ChatID)
+	config.SecurityChatID = normalizeChat("TELEGRAM_CHAT_ID_SECURITY", config.SecurityChatID)
+	config.PackagesChatID = normalizeChat("TELEGRAM_CHAT_ID_PACKAGES", config.PackagesChatID)
+	config.ComplianceChatID = normalizeChat("TELEGRAM_CHAT_ID_COMPLIANCE", config.ComplianceChatID)
+	config.InfraChatID = normalizeChat("TELEGRAM_CHAT_ID_INFRA", config.InfraChatID)
+	for path, chatId := range config.Routes {
+		config.Routes[path] = normalizeChat(path, chatId)
+	}
+	for login, chatId := range config.UserMap {
+		if id := normalizeChat("USER_MAP", chatId); id != "" {
+			config.UserMap[login] = id
+		} else {
+			delete(config.UserMap, login)
+		}
+	}
+	for i, rule := range config.RouteRules {
+		config.RouteRules[i].ChatID = normalizeChat(rule.Pattern, rule.ChatID)
+	}
+	parseModes := map[string]string{}
+	for chatId, mode := range config.ParseModes {
+		parseModes[normalizeChat("PARSE_MODES", chatId)] = mode
+	}
+	config.ParseModes = parseModes
+	return config, nil
+}
+
+// ConfigFromFile reads the configuration from a JSON file. Its keys are the
+// names of the fields of the Config, as in:
+//
+//	{"Token": "...", "GitHub": {"Secret": "...", "SelfLogin": "telebot"}}
+//
+// The durations are written as in the environment variables, such as "30s",
+// and so are the networks, as in "10.0.0.0/8".
+func ConfigFromFile(path string) (Config, error) {
+	var config Config
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("telebot: can't read the config file, %s", err)
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Config{}, fmt.Errorf("telebot: invalid config file %s, %s", path, err)
+	}
+	raw, err = fromText(reflect.TypeOf(config), raw)
+	if err == nil {
+		data, err = json.Marshal(raw)
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("telebot: invalid config file %s, %s", path, err)
+	}
+	return config, nil
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	networkType  = reflect.TypeOf(&net.IPNet{})
+)
+
+// fromText replaces the durations and the networks written as text in the
+// decoded JSON with what their types decode from, going through the fields of
+// the type.
+func fromText(t reflect.Type, value interface{}) (interface{}, error) {
+	text, isText := value.(string)
+	switch {
+	case t == durationType && isText:
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return nil, err
+		}
+		return int64(d), nil
+	case t == networkType && isText:
+		_, network, err := net.ParseCIDR(text)
+		if err != nil {
+			return nil, err
+		}
+		return network, nil
+	}
+
+	var err error
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		for key, item := range object {
+			field, ok := t.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, key) })
+			if !ok {
+				continue
+			}
+			if object[key], err = fromText(field.Type, item); err != nil {
+				return nil, fmt.Errorf("%s: %s", key, err)
+			}
+		}
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			return value, nil
+		}
+		for i, item := range list {
+			if list[i], err = fromText(t.Elem(), item); err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		for key, item := range object {
+			if object[key], err = fromText(t.Elem(), item); err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Ptr:
+		return fromText(t.Elem(), value)
+	}
+	return value, nil
+}
+
+// envFields returns what each environment variable sets in the config. They're
+// only called for the variables that are set, so that they override the config
+// file even when they're empty or false.
+func envFields(c *Config) map[string]func(name string) {
+	o := &c.GitHub
+	return map[string]func(name string){
+		"GITHUB_CLIENT_SECRET":        setString(&o.Secret),
+		"ACCEPT_UNSIGNED":             setBool(&o.AcceptUnsigned),
+		"TRUSTED_CIDRS":               func(name string) { o.TrustedNetworks = envNetworks(name) },
+		"SELF_LOGIN":                  setString(&o.SelfLogin),
+		"ALLOWED_SENDERS":             func(name string) { o.AllowedSenders = envList(name) },
+		"PUBLIC_ONLY":                 setBool(&o.PublicOnly),
+		"MAX_EVENT_AGE":               setDuration(&o.MaxEventAge),
+		"STATUS_EMOJI":                func(name string) { o.StatusEmoji = envMap(name) },
+		"STATUS_STATES":               func(name string) { o.StatusStates = envList(name) },
+		"ALERT_MENTIONS":              func(name string) { o.AlertMentions = envMap(name) },
+		"STATUS_PEOPLE":               setString(&o.StatusPeople),
+		"SEVERITIES":                  func(name string) { o.Severities = envMap(name) },
+		"REOPENED_MARKER":             setString(&o.ReopenedMarker),
+		"PR_EVENTS":                   setString(&o.PREvents),
+		"PR_SIZE":                     setBool(&o.PRSize),
+		"PR_SIZE_THRESHOLDS":          func(name string) { o.PRSizeThresholds = envInts(name) },
+		"ENABLE_ACTIONS":              func(name string) { o.EnabledActions = envList(name) },
+		"IGNORE_ACTIONS":              func(name string) { o.IgnoredActions = envList(name) },
+		"ENABLE_EVENTS":               func(name string) { o.EnabledEvents = envList(name) },
+		"EXTRACT_IMAGES":              setBool(&o.ExtractImages),
+		"ALWAYS_NOTIFY":               func(name string) { o.AlwaysNotify = envList(name) },
+		"INLINE_BUTTONS":              setBool(&o.InlineButtons),
+		"SENDER_FORMAT":               setString(&o.SenderFormat),
+		"AUTHOR_BADGES":               func(name string) { o.Badges = envList(name) },
+		"MAX_BODY_LEN":                setInt(&o.MaxBodyLen),
+		"MIN_COMMENT_LEN":             setInt(&o.MinCommentLen),
+		"DELETED_COMMENT_PREVIEW":     setInt(&o.DeletedPreview),
+		"REDACT_SECRETS":              setBool(&o.Redact),
+		"REDACT_PATTERNS":             func(name string) { o.RedactPatterns = envPatterns(name) },
+		"QUOTE_BODIES":                setBool(&o.QuoteBodies),
+		"LINK_REFERENCES":             setBool(&o.LinkReferences),
+		"SHORTEN_EVENTS":              func(name string) { o.ShortenEvents = envList(name) },
+		"REPO_DISPLAY":                setString(&o.RepoDisplay),
+		"DETAILS_LABEL":               setString(&o.DetailsLabel),
+		"DEFAULT_EVENT_TEMPLATE":      setString(&o.DefaultEventTemplate),
+		"TELEGRAM_TOKEN":              setString(&c.Token),
+		"TELEGRAM_CHAT_ID":            setString(&c.ChatID),
+		"TELEGRAM_PROXY":              setString(&c.Proxy),
+		"TELEGRAM_CHAT_ID_SECURITY":   setString(&c.SecurityChatID),
+		"TELEGRAM_CHAT_ID_PACKAGES":   setString(&c.PackagesChatID),
+		"TELEGRAM_CHAT_ID_COMPLIANCE": setString(&c.ComplianceChatID),
+		"TELEGRAM_CHAT_ID_INFRA":      setString(&c.InfraChatID),
+		"PREVIEW_KINDS":               func(name string) { c.PreviewKinds = envSwitches(name) },
+		"SILENT_EVENTS":               setBool(&c.SilentEvents),
+		"MARKDOWN_ONLY":               setBool(&c.MarkdownOnly),
+		"LONG_MESSAGE":                setString(&c.LongMessage),
+		"PARSE_MODES":                 func(name string) { c.ParseModes = envMap(name) },
+		"APPROVAL_COUNTS":             setBool(&c.ApprovalCounts),
+		"REVIEW_SUMMARY":              setBool(&c.ReviewSummary),
+		"FOOTER":                      setString(&c.Footer),
+		"SEND_WORKERS":                setInt(&c.SendWorkers),
+		"STATUS_WINDOW":               setDuration(&c.StatusWindow),
+		"STATUS_FLUSH_ON_FAILURE":     setBool(&c.StatusFlushOnFailure),
+		"DIGEST_WINDOW":               setDuration(&c.DigestWindow),
+		"DEDUP_WINDOW":                setDuration(&c.DedupWindow),
+		"LOG_WINDOW":                  setDuration(&c.LogWindow),
+		"STATE_DIR":                   setString(&c.StateDir),
+		"BREAKER_FAILURES":            setInt(&c.BreakerFailures),
+		"BREAKER_COOLDOWN":            setDuration(&c.BreakerCooldown),
+		"THROTTLE_LIMIT":              setInt(&c.ThrottleLimit),
+		"THROTTLE_WINDOW":             setDuration(&c.ThrottleWindow),
+		"SUCCESS_STATUS":              setInt(&c.SuccessStatus),
+		"SUCCESS_BODY":                setString(&c.SuccessBody),
+		"FILTERED_BODY":               setString(&c.FilteredBody),
+		"RELAY_URL":                   setString(&c.RelayURL),
+		"RELAY_ONLY":                  setBool(&c.RelayOnly),
+		"TEAMS_WEBHOOK_URL":           setString(&c.TeamsURL),
+		"SHORTEN_LINKS":               setString(&c.ShortenLinks),
+		"USER_MAP":                    func(name string) { c.UserMap = envMap(name) },
+		"NOTIFIER_TIMEOUT":            setDuration(&c.NotifierTimeout),
+		"MAX_BODY_BYTES":              setInt64(&c.MaxBodyBytes),
+		"ROUTE_RULES":                 func(name string) { c.RouteRules = envRules(name) },
+		"ROUTE_FALLBACK":              setString(&c.RouteFallback),
+	}
+}
+
+// setString returns a setter of the field to the environment variable.
+func setString(field *string) func(name string) {
+	return func(name string) { *field = os.Getenv(name) }
+}
+
+// setBool returns a setter of the field to whether the environment variable is
+// "true".
+func setBool(field *bool) func(name string) {
+	return func(name string) { *field = os.Getenv(name) == "true" }
+}
+
+// setInt returns a setter of the field to the integer of the environment
+// variable. It's left as it is if the variable is empty or invalid.
+func setInt(field *int) func(name string) {
+	return func(name string) { *field = int(envInt(name, int64(*field))) }
+}
+
+// setInt64 is setInt for the int64 fields.
+func setInt64(field *int64) func(name string) {
+	return func(name string) { *field = envInt(name, *field) }
+}
+
+// setDuration returns a setter of the field to the duration of the environment
+// variable. It's left as it is if the variable is empty or invalid.
+func setDuration(field *time.Duration) func(name string) {
+	return func(name string) { *field = envDuration(name, *field) }
+}
+
+// envMap reads an environment variable with a comma separated list of
+// key=value pairs. Pairs without a value are ignored.
+func envMap(name string) map[string]string {
+	m := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(name), ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		m[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return m
+}
+
+// envSwitches reads an environment variable with a comma separated list of
+// key=on or key=off pairs.
+func envSwitches(name string) map[string]bool {
+	switches := map[string]bool{}
+	for key, value := range envMap(name) {
+		switches[key] = value == "on"
+	}
+	return switches
+}
+
+// envList reads an environment variable with a comma separated list.
+func envList(name string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// envNetworks reads an environment variable with a comma separated list of
+// CIDRs, as in "10.0.0.0/8,192.168.1.0/24". The invalid ones are skipped.
+func envNetworks(name string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range envList(name) {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Printf("telebot: invalid %s %q, skipping it", name, cidr)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// envPatterns reads an environment variable with a space separated list of
+// regular expressions, since they often have commas. The invalid ones are
+// skipped.
+func envPatterns(name string) []string {
+	var patterns []string
+	for _, pattern := range strings.Fields(os.Getenv(name)) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			log.Printf("telebot: invalid %s %q, skipping it", name, pattern)
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// envRules reads an environment variable with a space separated list of
+// pattern=chatID pairs, in order. The patterns are regular expressions, so
+// the pairs are split at their last "=". The pairs without a chat are skipped.
+func envRules(name string) []RouteRule {
+	var rules []RouteRule
+	for _, pair := range strings.Fields(os.Getenv(name)) {
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 || i == len(pair)-1 {
+			log.Printf("telebot: invalid %s %q, expected pattern=chatID, skipping it", name, pair)
+			continue
+		}
+		rules = append(rules, RouteRule{Pattern: pair[:i], ChatID: pair[i+1:]})
+	}
+	return rules
+}
+
+// envInts reads an environment variable with a comma separated list of
+// integers. The invalid ones are skipped.
+func envInts(name string) []int {
+	var ints []int
+	for _, item := range envList(name) {
+		i, err := strconv.Atoi(item)
+		if err != nil {
+			log.Printf("telebot: invalid %s %q, skipping it", name, item)
+			continue
+		}
+		ints = append(ints, i)
+	}
+	return ints
+}
+
+// envInt reads an integer out of an environment variable, falling back to the
+// default when it's empty or invalid.
+func envInt(name string, def int64) int64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("telebot: invalid %s %q, using %d", name, value, def)
+		return def
+	}
+	return i
+}
+
+// envDuration reads a duration out of an environment variable, as in "30s",
+// falling back to the default when it's empty or invalid.
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("telebot: invalid %s %q, using %s", name, value, def)
+		return def
+	}
+	return d
+}
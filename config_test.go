@@ -0,0 +1,134 @@
+package telebot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnvTrustedNetworks(t *testing.T) {
+	os.Setenv("ACCEPT_UNSIGNED", "true")
+	os.Setenv("TRUSTED_CIDRS", "10.0.0.0/8, not a network,192.168.1.0/24")
+	defer os.Unsetenv("ACCEPT_UNSIGNED")
+	defer os.Unsetenv("TRUSTED_CIDRS")
+
+	config, err := ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.True(t, config.GitHub.AcceptUnsigned)
+	assert.Len(t, config.GitHub.TrustedNetworks, 2)
+	assert.Equal(t, "192.168.1.0/24", config.GitHub.TrustedNetworks[1].String())
+}
+
+func TestConfigFromEnvChats(t *testing.T) {
+	os.Setenv("TELEGRAM_CHAT_ID_SECURITY", "999")
+	os.Setenv("TELEGRAM_CHAT_ID_PACKAGES", "777")
+	os.Setenv("TELEGRAM_CHAT_ID_COMPLIANCE", " -555")
+	defer os.Unsetenv("TELEGRAM_CHAT_ID_SECURITY")
+	defer os.Unsetenv("TELEGRAM_CHAT_ID_PACKAGES")
+	defer os.Unsetenv("TELEGRAM_CHAT_ID_COMPLIANCE")
+
+	config, err := ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, "999", config.SecurityChatID)
+	assert.Equal(t, "777", config.PackagesChatID)
+	assert.Equal(t, "555", config.ComplianceChatID)
+}
+
+func TestConfigFromEnvUserMap(t *testing.T) {
+	os.Setenv("USER_MAP", "octocat= +123456789,Codertocat=not a chat")
+	defer os.Unsetenv("USER_MAP")
+
+	config, err := ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"octocat": "123456789"}, config.UserMap)
+}
+
+func TestConfigFromEnvRouteRules(t *testing.T) {
+	os.Setenv("ROUTE_RULES", "^acme/team-a-=111 no-chat= ^acme/(x|y)=-222")
+	defer os.Unsetenv("ROUTE_RULES")
+
+	config, err := ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, []RouteRule{{Pattern: "^acme/team-a-", ChatID: "111"}, {Pattern: "^acme/(x|y)", ChatID: "222"}}, config.RouteRules)
+}
+
+func TestConfigFromFile(t *testing.T) {
+	config, err := ConfigFromFile("fixtures/config.json")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "file token", config.Token)
+	assert.Equal(t, "file secret", config.GitHub.Secret)
+	assert.Equal(t, []string{"failure", "error"}, config.GitHub.StatusStates)
+	assert.Equal(t, map[string]string{"/github/team-a": "123"}, config.Routes)
+	assert.Equal(t, 30*time.Second, config.DedupWindow)
+	assert.Len(t, config.GitHub.TrustedNetworks, 1)
+	assert.True(t, config.GitHub.TrustedNetworks[0].Contains(net.ParseIP("10.1.2.3")))
+}
+
+func TestConfigFromEnvOverridesFile(t *testing.T) {
+	os.Setenv("CONFIG_FILE", "fixtures/config.json")
+	os.Setenv("TELEGRAM_TOKEN", "env token")
+	os.Setenv("STATUS_STATES", "failure")
+	defer os.Unsetenv("CONFIG_FILE")
+	defer os.Unsetenv("TELEGRAM_TOKEN")
+	defer os.Unsetenv("STATUS_STATES")
+
+	config, err := ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, "env token", config.Token)
+	assert.Equal(t, []string{"failure"}, config.GitHub.StatusStates)
+	assert.Equal(t, "file secret", config.GitHub.Secret)
+	assert.Equal(t, "telebot", config.GitHub.SelfLogin)
+	assert.Equal(t, int64(DefaultMaxBodyBytes), config.MaxBodyBytes)
+	assert.True(t, config.GitHub.Redact)
+	assert.Equal(t, 100, config.GitHub.MaxBodyLen)
+}
+
+func TestConfigFromEnvTurnsOffFile(t *testing.T) {
+	os.Setenv("CONFIG_FILE", "fixtures/config.json")
+	os.Setenv("REDACT_SECRETS", "false")
+	os.Setenv("MAX_BODY_LEN", "0")
+	os.Setenv("GITHUB_CLIENT_SECRET", "")
+	defer os.Unsetenv("CONFIG_FILE")
+	defer os.Unsetenv("REDACT_SECRETS")
+	defer os.Unsetenv("MAX_BODY_LEN")
+	defer os.Unsetenv("GITHUB_CLIENT_SECRET")
+
+	config, err := ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.False(t, config.GitHub.Redact)
+	assert.Equal(t, 0, config.GitHub.MaxBodyLen)
+	assert.Equal(t, "", config.GitHub.Secret)
+	assert.Equal(t, "file token", config.Token)
+}
+
+// Intentional failures:
+
+func TestConfigFromFileMissing(t *testing.T) {
+	_, err := ConfigFromFile("fixtures/missing.json")
+	assert.NotNil(t, err)
+}
+
+func TestConfigFromFileInvalidDuration(t *testing.T) {
+	file, err := ioutil.TempFile("", "config")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString(`{"DedupWindow": "30 seconds"}`)
+	file.Close()
+
+	_, err = ConfigFromFile(file.Name())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "DedupWindow")
+}
+
+func TestConfigFromEnvMissingFile(t *testing.T) {
+	// The secret of the file would be lost, so it's an error.
+	os.Setenv("CONFIG_FILE", "fixtures/missing.json")
+	defer os.Unsetenv("CONFIG_FILE")
+
+	_, err := ConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package telebot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// dedup remembers the messages sent to each chat for a while, so that the
+// events that end up with the same message, such as two synchronize in a row,
+// are only sent once. What it remembers is kept in its store.
+type dedup struct {
+	window time.Duration
+	store  Store
+
+	// mu keeps the store from being checked while it's set.
+	mu sync.Mutex
+}
+
+func newDedup(window time.Duration, store Store) *dedup {
+	return &dedup{window: window, store: store}
+}
+
+// duplicate returns true if the same text was sent to the chat within the
+// window.
+func (d *dedup) duplicate(chatId string, text string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, ok := d.store.Get(dedupKey(chatId, text))
+	return ok
+}
+
+// record remembers that the text was sent to the chat. It's only called once
+// it's sent, so that the redeliveries of the ones that couldn't be aren't
+// dropped.
+func (d *dedup) record(chatId string, text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.store.Set(dedupKey(chatId, text), "", d.window); err != nil {
+		log.Print(err)
+	}
+}
+
+func dedupKey(chatId string, text string) string {
+	sum := sha256.Sum256([]byte(chatId + "\n" + text))
+	return "dedup:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,124 @@
+package telebot
+
+import (
+	"errors"
+	"github.com/berserktech/telebot/tg"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	d := newDedup(time.Minute, store)
+
+	assert.False(t, d.duplicate("123", "hello"))
+	// It's not a duplicate until it's recorded as sent.
+	assert.False(t, d.duplicate("123", "hello"))
+	d.record("123", "hello")
+	assert.True(t, d.duplicate("123", "hello"))
+	assert.False(t, d.duplicate("456", "hello"))
+	assert.False(t, d.duplicate("123", "bye"))
+
+	now = now.Add(time.Minute)
+	assert.False(t, d.duplicate("123", "hello"))
+}
+
+func TestDedupBounded(t *testing.T) {
+	store := NewMemoryStore()
+	d := newDedup(time.Minute, store)
+	for i := 0; i < maxStoreEntries+10; i++ {
+		d.record("123", strings.Repeat("a", i))
+	}
+	assert.Len(t, store.entries, maxStoreEntries)
+}
+
+func TestHandlerDedup(t *testing.T) {
+	client := &mockClient{}
+	bot := NewServerBot(Config{DedupWindow: time.Minute, MaxBodyBytes: DefaultMaxBodyBytes})
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+
+	for i := 0; i < 2; i++ {
+		request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+		request.Header.Add("X-GitHub-Event", "ping")
+		bot.Handler("123")(httptest.NewRecorder(), request)
+	}
+	bot.queue.wait()
+
+	assert.Len(t, client.sent, 1)
+}
+
+func TestHandlerDedupFailedSend(t *testing.T) {
+	client := &mockClient{}
+	failures := 1
+	bot := NewServerBot(Config{DedupWindow: time.Minute, MaxBodyBytes: DefaultMaxBodyBytes})
+	bot.newClient = func() (tg.TelegramClient, error) {
+		if failures > 0 {
+			failures--
+			return nil, errors.New("telegram is down")
+		}
+		return client, nil
+	}
+
+	// The redelivery of the message that couldn't be sent goes through, and
+	// the next one is dropped.
+	for i := 0; i < 3; i++ {
+		request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+		request.Header.Add("X-GitHub-Event", "ping")
+		bot.Handler("123")(httptest.NewRecorder(), request)
+		bot.queue.wait()
+	}
+
+	assert.Len(t, client.sent, 1)
+}
+
+func TestHandlerDedupQueuedTwice(t *testing.T) {
+	client := &mockClient{}
+	bot := NewServerBot(Config{DedupWindow: time.Minute, MaxBodyBytes: DefaultMaxBodyBytes})
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+
+	// Both are queued before the first one is sent.
+	bot.queue.push("123", func() { time.Sleep(20 * time.Millisecond) })
+	for i := 0; i < 2; i++ {
+		request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+		request.Header.Add("X-GitHub-Event", "ping")
+		bot.Handler("123")(httptest.NewRecorder(), request)
+	}
+	bot.queue.wait()
+
+	assert.Len(t, client.sent, 1)
+}
+
+func TestHandlerDedupReviewSummary(t *testing.T) {
+	client := &mockClient{}
+	bot := NewServerBot(Config{DedupWindow: time.Minute, ReviewSummary: true, MaxBodyBytes: DefaultMaxBodyBytes})
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+
+	pullRequest, err := ioutil.ReadFile("gh/fixtures/github_pull_request.json")
+	assert.Nil(t, err)
+	review, err := ioutil.ReadFile("gh/fixtures/github_pull_request_review.json")
+	assert.Nil(t, err)
+	approved := strings.Replace(string(review), `"state": "commented"`, `"state": "approved"`, 1)
+
+	// The pull request is sent again with another summary of its reviews,
+	// but it's still the same event.
+	for _, event := range []struct{ name, body string }{
+		{"pull_request", string(pullRequest)},
+		{"pull_request_review", approved},
+		{"pull_request", string(pullRequest)},
+	} {
+		request := httptest.NewRequest("POST", "/", strings.NewReader(event.body))
+		request.Header.Add("X-GitHub-Event", event.name)
+		bot.Handler("123")(httptest.NewRecorder(), request)
+		bot.queue.wait()
+	}
+
+	assert.Len(t, client.sent, 2)
+	assert.Contains(t, client.sent[1].(tgbotapi.MessageConfig).Text, "reviews: 1 approved")
+}
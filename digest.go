@@ -0,0 +1,108 @@
+package telebot
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/berserktech/telebot/gh"
+	"github.com/berserktech/telebot/tg"
+)
+
+// digest holds the messages of each chat for a while, so that they're sent
+// together, grouped by repository, instead of one by one.
+type digest struct {
+	window time.Duration
+	// flush is called with the messages of a chat once its window is over.
+	flush func(chatId string, messages []gh.Message)
+
+	mu      sync.Mutex
+	batches map[string]*digestBatch
+}
+
+// digestBatch holds the messages of a chat, in the order they came.
+type digestBatch struct {
+	messages []gh.Message
+}
+
+func newDigest(window time.Duration, flush func(string, []gh.Message)) *digest {
+	return &digest{window: window, flush: flush, batches: map[string]*digestBatch{}}
+}
+
+// add holds the message until the window of its chat is over. The window
+// starts with the first message.
+func (d *digest) add(chatId string, message gh.Message) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	batch, ok := d.batches[chatId]
+	if !ok {
+		batch = &digestBatch{}
+		d.batches[chatId] = batch
+		time.AfterFunc(d.window, func() { d.flushBatch(chatId) })
+	}
+	batch.messages = append(batch.messages, message)
+}
+
+func (d *digest) flushBatch(chatId string) {
+	d.mu.Lock()
+	batch := d.batches[chatId]
+	delete(d.batches, chatId)
+	d.mu.Unlock()
+
+	d.flush(chatId, batch.messages)
+}
+
+// digested returns true if the message can wait for the digest. The security
+// and compliance alerts can't.
+func digested(message gh.Message) bool {
+	return !message.Security() && !message.Compliance()
+}
+
+// formatDigest returns the messages grouped by repository, in the order the
+// repositories came, each group under the bold name of its repository. The
+// digests longer than what Telegram accepts are split into more than one
+// text, repeating the name of the repository they're split at.
+func formatDigest(messages []gh.Message) []string {
+	var repos []string
+	groups := map[string][]string{}
+	for _, message := range messages {
+		if _, ok := groups[message.Repository]; !ok {
+			repos = append(repos, message.Repository)
+		}
+		groups[message.Repository] = append(groups[message.Repository], "• "+message.Text)
+	}
+
+	var texts []string
+	var text strings.Builder
+	var length int
+	for _, repo := range repos {
+		header := "*Other*"
+		if repo != "" {
+			header = "*" + gh.EscapeMarkdown(repo) + "*"
+		}
+		for i, entry := range groups[repo] {
+			block := "\n" + entry
+			if i == 0 {
+				block = header + block
+				if length > 0 {
+					block = "\n\n" + block
+				}
+			}
+			// The header is repeated at the top of the next text.
+			if length > 0 && length+utf8.RuneCountInString(block) > tg.MaxMessageLength {
+				texts = append(texts, text.String())
+				text.Reset()
+				length = 0
+				block = header + "\n" + entry
+			}
+			text.WriteString(block)
+			length += utf8.RuneCountInString(block)
+		}
+	}
+	if length > 0 {
+		texts = append(texts, text.String())
+	}
+	return texts
+}
@@ -0,0 +1,64 @@
+package telebot
+
+import (
+	"github.com/berserktech/telebot/gh"
+	"github.com/berserktech/telebot/tg"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestFormatDigest(t *testing.T) {
+	texts := formatDigest([]gh.Message{
+		{Text: "opened #1", Repository: "Codertocat/Hello-World"},
+		{Text: "opened #2", Repository: "Codertocat/hello_docs"},
+		{Text: "closed #1", Repository: "Codertocat/Hello-World"},
+		{Text: "ping"},
+	})
+
+	expected := "*Codertocat/Hello-World*\n• opened #1\n• closed #1\n\n*Codertocat/hello\\_docs*\n• opened #2\n\n*Other*\n• ping"
+	assert.Equal(t, []string{expected}, texts)
+}
+
+func TestFormatDigestSplit(t *testing.T) {
+	var messages []gh.Message
+	for i := 0; i < 3; i++ {
+		messages = append(messages, gh.Message{Text: strings.Repeat("a", 2000), Repository: "Codertocat/Hello-World"})
+	}
+
+	texts := formatDigest(messages)
+	assert.Len(t, texts, 2)
+	for _, text := range texts {
+		assert.True(t, strings.HasPrefix(text, "*Codertocat/Hello-World*\n"))
+		assert.True(t, utf8.RuneCountInString(text) <= tg.MaxMessageLength)
+	}
+}
+
+func TestHandlerDigest(t *testing.T) {
+	client := &mockClient{}
+	bot := NewServerBot(Config{DigestWindow: 20 * time.Millisecond, MaxBodyBytes: DefaultMaxBodyBytes})
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+
+	for i := 0; i < 2; i++ {
+		request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+		request.Header.Add("X-GitHub-Event", "ping")
+		recorder := httptest.NewRecorder()
+		bot.Handler("123")(recorder, request)
+		assert.Equal(t, "Batched:\nping", recorder.Body.String())
+	}
+	time.Sleep(50 * time.Millisecond)
+	bot.queue.wait()
+
+	assert.Len(t, client.sent, 1)
+	assert.Equal(t, "*Other*\n• ping\n• ping", client.sent[0].(tgbotapi.MessageConfig).Text)
+}
+
+func TestDigestSkipsAlerts(t *testing.T) {
+	assert.True(t, digested(gh.Message{Event: "issues"}))
+	assert.False(t, digested(gh.Message{Event: "security_advisory"}))
+	assert.False(t, digested(gh.Message{Event: "branch_protection_rule"}))
+}
@@ -0,0 +1,34 @@
+package telebot
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/berserktech/telebot/gh"
+	"github.com/berserktech/telebot/tg"
+)
+
+// withFooter appends the Config.Footer to the text of the message, on its own
+// line, with its {repo}, {event} and {sender} placeholders replaced by the
+// ones of the message. It's written in Markdown, like the messages, so it's
+// converted to the parse mode of each chat along with them.
+//
+// The footer is left out of the messages it would make longer than Telegram
+// accepts, unless they're split, and out of the ones of the bot itself, such
+// as the SelfTestMessage, which aren't about any event.
+func (b *Bot) withFooter(message gh.Message) string {
+	if b.config.Footer == "" || message.Text == "" || message.Event == "" {
+		return message.Text
+	}
+
+	footer := strings.NewReplacer(
+		"{repo}", gh.EscapeMarkdown(message.Repository),
+		"{event}", gh.EscapeMarkdown(message.Event),
+		"{sender}", gh.EscapeMarkdown(message.Sender),
+	).Replace(b.config.Footer)
+	text := message.Text + "\n" + strings.TrimSpace(footer)
+	if b.config.LongMessage != "split" && utf8.RuneCountInString(text) > tg.MaxMessageLength {
+		return message.Text
+	}
+	return text
+}
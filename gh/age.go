@@ -0,0 +1,102 @@
+package gh
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// now returns the current time. It's replaced by the tests.
+var now = time.Now
+
+// timestamp is a time in a payload, which can be either RFC 3339 or a Unix
+// timestamp, as the ones of the repositories of the pushes are.
+type timestamp struct {
+	time.Time
+	ok bool
+}
+
+func (t *timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		parsed, err := time.Parse(time.RFC3339, s)
+		t.Time, t.ok = parsed, err == nil
+		return nil
+	}
+	if seconds, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		t.Time, t.ok = time.Unix(seconds, 0), true
+	}
+	return nil
+}
+
+// timeOf returns when the event happened, or false if the payload doesn't say.
+// It's the time the object it's about was last updated, or pushed for the
+// pushes. The time of the head commit is the last resort, since it's when it
+// was authored: the rebased and cherry-picked commits are pushed long after.
+// The dismissed and deleted objects, and the edited reviews, keep the times of
+// before, so they don't say.
+func timeOf(payload []byte) (time.Time, bool) {
+	type timestamps struct {
+		UpdatedAt   timestamp `json:"updated_at"`
+		SubmittedAt timestamp `json:"submitted_at"`
+		PushedAt    timestamp `json:"pushed_at"`
+		Timestamp   timestamp `json:"timestamp"`
+	}
+	var p struct {
+		timestamps
+		Action      string      `json:"action"`
+		Comment     timestamps  `json:"comment"`
+		Review      *timestamps `json:"review"`
+		PullRequest timestamps  `json:"pull_request"`
+		Issue       timestamps  `json:"issue"`
+		Repository  timestamps  `json:"repository"`
+		HeadCommit  *timestamps `json:"head_commit"`
+	}
+	json.Unmarshal(payload, &p)
+
+	switch {
+	case p.Action == "dismissed" || p.Action == "deleted":
+		return time.Time{}, false
+	case p.Review != nil && p.Action != "submitted":
+		return time.Time{}, false
+	}
+
+	var candidates []timestamp
+	if p.Review != nil {
+		candidates = append(candidates, p.Review.SubmittedAt)
+	}
+	candidates = append(candidates,
+		p.Comment.UpdatedAt,
+		p.PullRequest.UpdatedAt,
+		p.Issue.UpdatedAt,
+		p.UpdatedAt,
+	)
+	// The repositories are pushed to by other events too, so the time of the
+	// push is only the one of the event for the pushes, which have a head
+	// commit.
+	if p.HeadCommit != nil {
+		candidates = append(candidates, p.Repository.PushedAt, p.HeadCommit.Timestamp)
+	}
+	for _, t := range candidates {
+		if t.ok {
+			return t.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// notAllowedAge returns an error if there's a MaxEventAge, and the event
+// happened before it. The events without a timestamp are allowed.
+func (o Options) notAllowedAge(payload []byte) error {
+	if o.MaxEventAge <= 0 {
+		return nil
+	}
+	t, ok := timeOf(payload)
+	if !ok {
+		return nil
+	}
+	if age := now().Sub(t); age > o.MaxEventAge {
+		return filtered("gh: not allowed event age, %s", age.Round(time.Second))
+	}
+	return nil
+}
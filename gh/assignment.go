@@ -0,0 +1,25 @@
+package gh
+
+import (
+	"fmt"
+
+	"gopkg.in/go-playground/webhooks.v5/github"
+)
+
+// assignment returns the text of the assigned and unassigned actions, naming
+// the assignee, as in "X assigned Y to issue #2: Title url". It returns false
+// for the other actions, or if the payload has no assignee.
+func assignment(c Content, s Sender, assignee *github.Assignee, kind string, number int64, o Options) (string, bool) {
+	if assignee == nil || (c.Action != "assigned" && c.Action != "unassigned") {
+		return "", false
+	}
+
+	preposition := "to"
+	if c.Action == "unassigned" {
+		preposition = "from"
+	}
+	return fmt.Sprintf(
+		"%s %s %s %s %s #%d: %s %s",
+		s.Link(), c.Action, o.sender(assignee.Login, assignee.HTMLURL).Link(), preposition, kind, number, c.Title, c.HTMLURL,
+	), true
+}
@@ -0,0 +1,72 @@
+package gh
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DefaultBadges are the author associations badged when Options.Badges is
+// empty: the ones of the people who aren't regular members.
+var DefaultBadges = []string{"CONTRIBUTOR", "FIRST_TIME_CONTRIBUTOR", "FIRST_TIMER"}
+
+// badges are how the author associations are shown, after the sender.
+var badges = map[string]string{
+	"OWNER":                  "(owner)",
+	"MEMBER":                 "(member)",
+	"COLLABORATOR":           "(collaborator)",
+	"CONTRIBUTOR":            "(contributor)",
+	"FIRST_TIME_CONTRIBUTOR": "(first-time contributor)",
+	"FIRST_TIMER":            "(first-timer)",
+	"MANNEQUIN":              "(mannequin)",
+	"NONE":                   "(outside contributor)",
+}
+
+// associationOf reads the author association of the comment, the review, the
+// pull request or the issue, in that order, out of the raw payload. It's empty
+// unless its author is the sender, who's the one shown in the message.
+func associationOf(payload []byte) string {
+	type authored struct {
+		User              rawUser `json:"user"`
+		AuthorAssociation string  `json:"author_association"`
+	}
+	var p struct {
+		Comment     authored `json:"comment"`
+		Review      authored `json:"review"`
+		PullRequest authored `json:"pull_request"`
+		Issue       authored `json:"issue"`
+		Sender      rawUser  `json:"sender"`
+	}
+	json.Unmarshal(payload, &p)
+	for _, a := range []authored{p.Comment, p.Review, p.PullRequest, p.Issue} {
+		if a.AuthorAssociation != "" {
+			if a.User.Login != p.Sender.Login {
+				return ""
+			}
+			return a.AuthorAssociation
+		}
+	}
+	return ""
+}
+
+// withBadge puts the badge of the author association of the event after the
+// first time the sender shows up in the message, if it's one of the Badges
+// (or of the DefaultBadges), as in "octocat (first-time contributor)
+// commented...". Nothing's badged if the Badges are "none".
+func (o Options) withBadge(payload []byte, message string) string {
+	allowed := o.Badges
+	if len(allowed) == 0 {
+		allowed = DefaultBadges
+	}
+	association := associationOf(payload)
+	badge := badges[association]
+	if badge == "" || !contains(allowed, association) {
+		return message
+	}
+
+	var p struct {
+		Sender rawUser `json:"sender"`
+	}
+	json.Unmarshal(payload, &p)
+	sender := p.Sender.sender(o).Link()
+	return strings.Replace(message, sender, sender+" "+badge, 1)
+}
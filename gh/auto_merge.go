@@ -0,0 +1,20 @@
+package gh
+
+import "fmt"
+
+// autoMerge returns the text of the auto_merge_enabled and auto_merge_disabled
+// actions of the pull requests, as in "X enabled auto-merge on PR #1: Title
+// url". It returns false for the other actions.
+func autoMerge(c Content, s Sender, number int64) (string, bool) {
+	var verb string
+	switch c.Action {
+	case "auto_merge_enabled":
+		verb = "enabled"
+	case "auto_merge_disabled":
+		verb = "disabled"
+	default:
+		return "", false
+	}
+
+	return fmt.Sprintf("%s %s auto-merge on PR #%d: %s %s", s.Link(), verb, number, c.Title, c.HTMLURL), true
+}
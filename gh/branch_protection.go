@@ -0,0 +1,41 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ComplianceMarker starts every message about the rules of the repositories,
+// so they stand out.
+const ComplianceMarker = "🛡️"
+
+// branchProtectionRulePayload holds the fields we use of the
+// branch_protection_rule event.
+type branchProtectionRulePayload struct {
+	Action string `json:"action"`
+	Rule   struct {
+		Name string `json:"name"`
+	} `json:"rule"`
+	Repository rawRepository `json:"repository"`
+	Sender     rawUser       `json:"sender"`
+}
+
+// formatBranchProtectionRule reports the changes to the protection of the
+// branches. They're compliance alerts, so the ignored actions (such as
+// "edited") don't apply to them.
+func formatBranchProtectionRule(payload []byte, o Options) (string, error) {
+	var p branchProtectionRulePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+
+	switch p.Action {
+	case "created", "edited", "deleted":
+		return fmt.Sprintf(
+			"%s %s %s the protection rule of `%s` in %s",
+			ComplianceMarker, p.Sender.sender(o).Link(), p.Action, p.Rule.Name, p.Repository.link(),
+		), nil
+	}
+
+	return "", o.allow(filtered("gh: not allowed branch protection rule action, %s", p.Action))
+}
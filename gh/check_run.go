@@ -0,0 +1,58 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// checkRunPayload holds the fields we use of the check_run event, which the
+// webhooks library doesn't know.
+type checkRunPayload struct {
+	Action   string `json:"action"`
+	CheckRun struct {
+		Name        string    `json:"name"`
+		Conclusion  string    `json:"conclusion"`
+		HTMLURL     string    `json:"html_url"`
+		StartedAt   time.Time `json:"started_at"`
+		CompletedAt time.Time `json:"completed_at"`
+		App         struct {
+			Name string `json:"name"`
+		} `json:"app"`
+	} `json:"check_run"`
+}
+
+// checkRunFailed returns true if the payload is of a failed check run.
+func checkRunFailed(payload []byte) bool {
+	var p checkRunPayload
+	return json.Unmarshal(payload, &p) == nil && p.Action == "completed" && failedConclusion(p.CheckRun.Conclusion)
+}
+
+// formatCheckRun reports the completed check runs, and how long they took, as
+// in "Check `build` by `GitHub Actions` completed with `success` in 1m5s". The
+// created, rerequested and requested_action ones are dropped.
+func formatCheckRun(payload []byte, o Options) (string, error) {
+	var p checkRunPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	if p.Action != "completed" {
+		return "", o.allow(filtered("gh: not allowed check run action, %s", p.Action))
+	}
+
+	run := p.CheckRun
+	emoji := o.statusEmoji()["success"]
+	if failedConclusion(run.Conclusion) {
+		emoji = o.statusEmoji()["failure"]
+	}
+	message := fmt.Sprintf("%s Check `%s`", emoji, run.Name)
+	if run.App.Name != "" {
+		message += fmt.Sprintf(" by `%s`", run.App.Name)
+	}
+	message += fmt.Sprintf(" completed with `%s`", run.Conclusion)
+	if d := runDuration(run.StartedAt, run.CompletedAt); d > 0 {
+		message += " in " + formatDuration(d)
+	}
+
+	return message + ": " + run.HTMLURL, nil
+}
@@ -0,0 +1,18 @@
+package gh
+
+import "fmt"
+
+// closed returns the text of the closed action of the issues, naming who opened
+// the issue if it's someone else than who closed it, as in "X closed issue #2
+// (opened by Y): Title url". It returns false for the other actions.
+func closed(c Content, s Sender, author Sender, number int64) (string, bool) {
+	if c.Action != "closed" {
+		return "", false
+	}
+
+	var openedBy string
+	if author.Login != "" && author.Login != s.Login {
+		openedBy = fmt.Sprintf(" (opened by %s)", author.Link())
+	}
+	return fmt.Sprintf("%s closed issue #%d%s: %s%s %s", s.Link(), number, openedBy, c.Title, c.labels(), c.HTMLURL), true
+}
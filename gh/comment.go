@@ -1,17 +1,106 @@
 package gh
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Comment struct {
+	// Action is what happened to the comment: "created" (the default),
+	// "edited" or "deleted".
+	Action  string
 	Body    string
 	HTMLURL string
+	// Path and Line are set when the comment targets a specific line of a
+	// file, which only commit comments can do.
+	Path string
+	Line int64
+	// DiffHunk is the code the comment is about, which only pull request
+	// review comments have.
+	DiffHunk string
 }
 
-// Returns a formatted message saying who commented what, and where
+// MaxHunkLines is how many lines of the diff hunk are shown. The last ones are
+// kept, since the comments target the end of the hunk.
+const MaxHunkLines = 4
+
+// newComment returns the comment, unless its action or its length aren't
+// allowed. The deleted comments only keep a preview of their body, if
+// Options.DeletedPreview is set.
+func newComment(action string, body string, htmlURL string, o Options) (Comment, error) {
+	comment := Comment{Action: action, HTMLURL: htmlURL}
+	if err := o.allow(comment.NotAllowed(o)); err != nil {
+		return Comment{}, err
+	}
+	if err := o.allow(o.notAllowedComment(body)); err != nil {
+		return Comment{}, err
+	}
+
+	if action != "deleted" {
+		comment.Body = o.body(body)
+	} else if o.DeletedPreview > 0 {
+		comment.Body = clamp(o.redact(body), o.DeletedPreview)
+	}
+	return comment, nil
+}
+
+// Returns a formatted message saying who commented what, and where. GitHub's
+// emoji shortcodes are replaced with their emoji, since Telegram shows them as
+// text. The deleted comments only say where they were, with the preview of
+// their body if there's one.
 func (c Comment) Format(kind string, s Sender) string {
-	return fmt.Sprintf(`%s commented one %s with:
+	if c.Action == "deleted" {
+		if c.Body == "" {
+			return fmt.Sprintf("%s deleted a comment on the %s%s: %s", s.Link(), kind, c.location(), c.HTMLURL)
+		}
+		return fmt.Sprintf("%s deleted a comment on the %s%s:\n\n%s\n\n%s", s.Link(), kind, c.location(), emojize(c.Body), c.HTMLURL)
+	}
 
-%s
+	return fmt.Sprintf(`%s %s%s with:
+
+%s%s
+
+%s`, s.Link(), c.verb(kind), c.location(), c.hunk(), emojize(c.Body), c.HTMLURL)
+}
+
+// verb returns what the sender did to the comment on the kind of thing.
+func (c Comment) verb(kind string) string {
+	switch c.Action {
+	case "edited":
+		return "edited a comment on the " + kind
+	}
+	return "commented one " + kind
+}
+
+// NotAllowed returns an error if the comment's action is one of the ignored
+// ones. The deleted comments are only sent if they're in the EnabledActions.
+func (c Comment) NotAllowed(o Options) error {
+	if c.Action == "deleted" && !contains(o.EnabledActions, "deleted") {
+		return filtered("gh: not allowed action, deleted")
+	}
+	return Content{Action: c.Action}.NotAllowed(o)
+}
+
+// hunk returns the last lines of the diff hunk as a code block, if there's a
+// diff hunk.
+func (c Comment) hunk() string {
+	if c.DiffHunk == "" {
+		return ""
+	}
+	lines := strings.Split(c.DiffHunk, "\n")
+	if len(lines) > MaxHunkLines {
+		lines = lines[len(lines)-MaxHunkLines:]
+	}
+	return "```\n" + strings.Join(lines, "\n") + "\n```\n"
+}
 
-%s`, s.Link(), kind, c.Body, c.HTMLURL)
+// location returns the file and line the comment targets, if any.
+func (c Comment) location() string {
+	if c.Path == "" {
+		return ""
+	}
+	if c.Line == 0 {
+		return " " + c.Path
+	}
+	return fmt.Sprintf(" %s:%d", c.Path, c.Line)
 }
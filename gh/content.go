@@ -1,39 +1,98 @@
 package gh
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Content struct {
 	Action  string
 	Title   string
 	HTMLURL string
 	Body    string
+	// Labels are shown when the issue or pull request is opened.
+	Labels []string
+	// DetailsLabel goes before the body. The body goes on its own line
+	// without a label if it's empty.
+	DetailsLabel string
 }
 
+// DefaultDetailsLabel is the DetailsLabel when Options.DetailsLabel is empty.
+const DefaultDetailsLabel = "Details:"
+
 // Format returns a string already formatted to be sent as a message.
 func (c Content) Format(kind string, s Sender) string {
-	var body string
-	if c.Body != "" {
-		body = fmt.Sprintf(" Details:\n%s", c.Body)
-	}
-
 	return fmt.Sprintf(
-		"%s %s the %s: %s %s%s",
-		s.Link(), c.Action, kind, c.Title, c.HTMLURL, body,
+		"%s %s the %s: %s%s %s%s",
+		s.Link(), c.Action, kind, c.Title, c.labels(), c.HTMLURL, c.details(),
 	)
 }
 
-// NotAllowed returns an error if the received Action is not valid.
-func (c Content) NotAllowed() error {
-	switch c.Action {
-	case "labeled",
-		"unlabeled",
-		"assigned",
-		"unassigned",
-		"review_requested",
-		"review_request_removed",
-		"edited",
-		"synchronize":
-		return fmt.Errorf("gh: not allowed action, %s", c.Action)
+// details returns the body after its label, or on its own line if there's no
+// label. It's empty if there's no body.
+func (c Content) details() string {
+	if c.Body != "" && c.DetailsLabel != "" {
+		return fmt.Sprintf(" %s\n%s", c.DetailsLabel, c.Body)
+	} else if c.Body != "" {
+		return "\n" + c.Body
+	}
+	return ""
+}
+
+// labels returns the labels as a bracketed list, as in " [bug][p1]".
+func (c Content) labels() string {
+	if c.Action != "opened" || len(c.Labels) == 0 {
+		return ""
+	}
+
+	var labels strings.Builder
+	labels.WriteString(" ")
+	for _, label := range c.Labels {
+		labels.WriteString(EscapeMarkdown("[" + label + "]"))
+	}
+	return labels.String()
+}
+
+// DefaultIgnoredActions are the actions dropped unless they're enabled through
+// the Options. They're either too noisy or not that interesting.
+var DefaultIgnoredActions = []string{
+	"labeled",
+	"unlabeled",
+	"assigned",
+	"unassigned",
+	"review_requested",
+	"review_request_removed",
+	"edited",
+	"synchronize",
+	"pinned",
+	"unpinned",
+	"milestoned",
+	"demilestoned",
+	"auto_merge_enabled",
+	"auto_merge_disabled",
+	"queued",
+	"in_progress",
+	"waiting",
+}
+
+// DiffStatsActions are the actions of the pull requests whose messages show
+// their additions and deletions. Once they're closed, the diff is old news.
+var DiffStatsActions = []string{
+	"opened",
+	"synchronize",
+	"ready_for_review",
+}
+
+// DefaultDisabledEvents are the events dropped unless they're enabled through
+// the Options, since they're too noisy for most chats.
+var DefaultDisabledEvents = []string{
+	"reaction",
+}
+
+// NotAllowed returns an error if the received Action is ignored.
+func (c Content) NotAllowed(o Options) error {
+	if o.ignoresAction(c.Action) {
+		return filtered("gh: not allowed action, %s", c.Action)
 	}
 
 	return nil
@@ -0,0 +1,39 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// reviewDismissedPayload holds the fields we use of the dismissed action of the
+// pull request reviews. The webhooks library drops the dismissal message.
+type reviewDismissedPayload struct {
+	Action string `json:"action"`
+	Review struct {
+		DismissalMessage string `json:"dismissal_message"`
+	} `json:"review"`
+	PullRequest rawIssue `json:"pull_request"`
+	Sender      rawUser  `json:"sender"`
+}
+
+// formatReviewDismissed reports the reviews dismissed, so the pull request isn't
+// taken as approved, as in "X dismissed a review on PR #1 (reason: outdated)".
+func formatReviewDismissed(payload []byte, o Options) (string, error) {
+	var p reviewDismissedPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	if err := o.allow(Content{Action: p.Action}.NotAllowed(o)); err != nil {
+		return "", err
+	}
+
+	var reason string
+	if p.Review.DismissalMessage != "" {
+		reason = fmt.Sprintf(" (reason: %s)", EscapeMarkdown(p.Review.DismissalMessage))
+	}
+
+	return fmt.Sprintf(
+		"%s dismissed a review on PR #%d%s: %s %s",
+		p.Sender.sender(o).Link(), p.PullRequest.Number, reason, p.PullRequest.Title, p.PullRequest.HTMLURL,
+	), nil
+}
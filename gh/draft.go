@@ -0,0 +1,20 @@
+package gh
+
+import "fmt"
+
+// draft returns the text of the converted_to_draft and ready_for_review actions
+// of the pull requests, as in "X converted PR #1 to draft: Title url". It
+// returns false for the other actions.
+func draft(c Content, s Sender, number int64) (string, bool) {
+	var did string
+	switch c.Action {
+	case "converted_to_draft":
+		did = fmt.Sprintf("converted PR #%d to draft", number)
+	case "ready_for_review":
+		did = fmt.Sprintf("marked PR #%d ready for review", number)
+	default:
+		return "", false
+	}
+
+	return fmt.Sprintf("%s %s: %s %s%s", s.Link(), did, c.Title, c.HTMLURL, c.details()), true
+}
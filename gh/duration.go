@@ -0,0 +1,17 @@
+package gh
+
+import "time"
+
+// runDuration returns how long a run took, out of when it started and when it
+// finished. It's zero if any of them is missing, or they make no sense.
+func runDuration(start time.Time, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// formatDuration shows the duration to the second, as in "4m12s".
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,71 @@
+package gh
+
+import "regexp"
+
+// emojiShortcode matches GitHub's emoji shortcodes, as in ":tada:".
+var emojiShortcode = regexp.MustCompile(`:[a-z0-9_+\-]+:`)
+
+// shortcodes maps the most common GitHub emoji shortcodes to their emoji. It
+// isn't the full list, the rest of the shortcodes are left as they are.
+var shortcodes = map[string]string{
+	":+1:":               "👍",
+	":thumbsup:":         "👍",
+	":-1:":               "👎",
+	":thumbsdown:":       "👎",
+	":tada:":             "🎉",
+	":rocket:":           "🚀",
+	":heart:":            "❤️",
+	":eyes:":             "👀",
+	":smile:":            "😄",
+	":laughing:":         "😆",
+	":confused:":         "😕",
+	":fire:":             "🔥",
+	":bug:":              "🐛",
+	":sparkles:":         "✨",
+	":warning:":          "⚠️",
+	":white_check_mark:": "✅",
+	":x:":                "❌",
+	":heavy_check_mark:": "✔️",
+	":memo:":             "📝",
+	":wrench:":           "🔧",
+	":lock:":             "🔒",
+	":zap:":              "⚡",
+	":boom:":             "💥",
+	":art:":              "🎨",
+	":construction:":     "🚧",
+	":pray:":             "🙏",
+	":clap:":             "👏",
+	":ok_hand:":          "👌",
+	":thinking:":         "🤔",
+	":100:":              "💯",
+	":recycle:":          "♻️",
+	":wave:":             "👋",
+	":star:":             "⭐",
+	":bulb:":             "💡",
+	":question:":         "❓",
+	":exclamation:":      "❗",
+	":rotating_light:":   "🚨",
+	":arrow_up:":         "⬆️",
+	":arrow_down:":       "⬇️",
+	":heavy_plus_sign:":  "➕",
+	":heavy_minus_sign:": "➖",
+	":lipstick:":         "💄",
+	":package:":          "📦",
+	":green_heart:":      "💚",
+	":see_no_evil:":      "🙈",
+	":wastebasket:":      "🗑️",
+	":hourglass:":        "⌛",
+	":speech_balloon:":   "💬",
+	":raised_hands:":     "🙌",
+	":stuck_out_tongue:": "😛",
+}
+
+// emojize replaces the known emoji shortcodes of the text with their emoji.
+func emojize(text string) string {
+	return emojiShortcode.ReplaceAllStringFunc(text, func(code string) string {
+		if emoji, ok := shortcodes[code]; ok {
+			return emoji
+		}
+		return code
+	})
+}
@@ -0,0 +1,67 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/go-playground/webhooks.v5/github"
+)
+
+// The kinds of errors of Parse, so that they can be told apart with KindOf.
+var (
+	// ErrFiltered is the kind of the events dropped on purpose, as set by
+	// the Options.
+	ErrFiltered = errors.New("gh: filtered event")
+	// ErrUnsupported is the kind of the events nobody parses.
+	ErrUnsupported = errors.New("gh: unsupported event")
+	// ErrSignature is the kind of the requests with a missing or invalid
+	// signature.
+	ErrSignature = errors.New("gh: invalid signature")
+	// ErrParse is the kind of every other error, such as the malformed
+	// payloads.
+	ErrParse = errors.New("gh: invalid payload")
+)
+
+// Error is an error returned by Parse, of one of the kinds above. Its message
+// is the one of the underlying error.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// KindOf returns the kind of an error returned by Parse: ErrFiltered,
+// ErrUnsupported, ErrSignature or ErrParse. It returns nil if there's no error.
+func KindOf(err error) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		return e.Kind
+	}
+	return ErrParse
+}
+
+// filtered returns an ErrFiltered with the formatted message.
+func filtered(format string, a ...interface{}) error {
+	return &Error{Kind: ErrFiltered, Err: fmt.Errorf(format, a...)}
+}
+
+// classify gives their kind to the errors that don't have one yet.
+func classify(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case github.ErrEventNotFound:
+		return &Error{Kind: ErrUnsupported, Err: err}
+	case github.ErrMissingHubSignatureHeader, github.ErrHMACVerificationFailed:
+		return &Error{Kind: ErrSignature, Err: err}
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return &Error{Kind: ErrParse, Err: err}
+}
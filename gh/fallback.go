@@ -0,0 +1,12 @@
+package gh
+
+// formatDefault returns the text of the message for the events nobody parses,
+// out of the DefaultEventTemplate. See fill for its placeholders.
+func formatDefault(event string, payload []byte, o Options) (string, error) {
+	action := actionOf(payload)
+	if err := o.allow(Content{Action: action}.NotAllowed(o)); err != nil {
+		return "", err
+	}
+
+	return fill(o.DefaultEventTemplate, event, payload, "", o)
+}
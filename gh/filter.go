@@ -0,0 +1,147 @@
+package gh
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// senderOf reads the login of the sender out of the raw payload. It's shared by
+// every event, so it's simpler to read it once than in each case of the switch.
+func senderOf(payload []byte) string {
+	var p struct {
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+	}
+	json.Unmarshal(payload, &p)
+	return p.Sender.Login
+}
+
+// urlOf reads the page of what the event is about out of the raw payload: the
+// comment, the review, the pull request, the issue or the commit, in that
+// order, falling back to the repository.
+func urlOf(payload []byte) string {
+	type page struct {
+		HTMLURL string `json:"html_url"`
+	}
+	var p struct {
+		Comment     page `json:"comment"`
+		Review      page `json:"review"`
+		PullRequest page `json:"pull_request"`
+		Issue       page `json:"issue"`
+		Commit      page `json:"commit"`
+		Repository  page `json:"repository"`
+	}
+	json.Unmarshal(payload, &p)
+	for _, url := range []string{p.Comment.HTMLURL, p.Review.HTMLURL, p.PullRequest.HTMLURL, p.Issue.HTMLURL, p.Commit.HTMLURL} {
+		if url != "" {
+			return url
+		}
+	}
+	return p.Repository.HTMLURL
+}
+
+// reviewOf reads the pull request, the state of the review and who wrote it
+// out of the raw payload of a pull_request_review, which may be parsed by us or
+// by the webhooks library.
+func reviewOf(payload []byte) (pullRequest string, state string, reviewer string) {
+	var p struct {
+		Review struct {
+			State string  `json:"state"`
+			User  rawUser `json:"user"`
+		} `json:"review"`
+		PullRequest struct {
+			HTMLURL string `json:"html_url"`
+		} `json:"pull_request"`
+	}
+	json.Unmarshal(payload, &p)
+	return p.PullRequest.HTMLURL, p.Review.State, p.Review.User.Login
+}
+
+// actionOf reads what happened out of the raw payload: the action of most of
+// the events, or the state of the statuses and the page builds.
+func actionOf(payload []byte) string {
+	var p struct {
+		Action string `json:"action"`
+		State  string `json:"state"`
+		Build  struct {
+			Status string `json:"status"`
+		} `json:"build"`
+	}
+	json.Unmarshal(payload, &p)
+	switch {
+	case p.Action != "":
+		return p.Action
+	case p.State != "":
+		return p.State
+	}
+	return p.Build.Status
+}
+
+// alwaysNotify returns true if the event, with the given action, is one of
+// the AlwaysNotify ones.
+func (o Options) alwaysNotify(event string, action string) bool {
+	return contains(o.AlwaysNotify, event) || contains(o.AlwaysNotify, event+":"+action)
+}
+
+// allow discards the error of a filter if the event must always be sent.
+func (o Options) allow(err error) error {
+	if o.force {
+		return nil
+	}
+	return err
+}
+
+// skipSender returns true if the events sent by the given login must be
+// dropped.
+func (o Options) skipSender(login string) bool {
+	return o.SelfLogin != "" && login == o.SelfLogin
+}
+
+// notAllowedSender returns an error if there are AllowedSenders, and the
+// given login isn't one of them.
+func (o Options) notAllowedSender(login string) error {
+	if len(o.AllowedSenders) > 0 && !contains(o.AllowedSenders, login) {
+		return filtered("gh: not allowed sender, %s", login)
+	}
+	return nil
+}
+
+// notAllowedRepository returns an error if only the public repositories are
+// wanted, and the repository of the event isn't known to be public.
+func (o Options) notAllowedRepository(payload []byte) error {
+	if !o.PublicOnly {
+		return nil
+	}
+
+	var p struct {
+		Repository struct {
+			Private *bool `json:"private"`
+		} `json:"repository"`
+	}
+	json.Unmarshal(payload, &p)
+	if p.Repository.Private == nil || *p.Repository.Private {
+		return filtered("gh: not allowed repository, not public")
+	}
+	return nil
+}
+
+// notAllowedComment returns an error if there's a MinCommentLen, and the body
+// of the comment is shorter, as in "lgtm".
+func (o Options) notAllowedComment(body string) error {
+	if length := utf8.RuneCountInString(strings.TrimSpace(body)); length < o.MinCommentLen {
+		return filtered("gh: not allowed comment, %d characters long", length)
+	}
+	return nil
+}
+
+// notAllowedPullRequest returns an error if only the merged pull requests are
+// wanted, and the event isn't the one of a pull request being merged.
+func (o Options) notAllowedPullRequest(action string, merged bool) error {
+	if o.PREvents == "merged" && (action != "closed" || !merged) {
+		return filtered("gh: not allowed pull request, not merged")
+	}
+
+	return nil
+}
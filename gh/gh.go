@@ -2,33 +2,252 @@
 package gh
 
 import (
-	"fmt"
 	"net/http"
+	"strings"
 
 	"gopkg.in/go-playground/webhooks.v5/github"
 )
 
-// Taken from: https://github.com/go-playground/webhooks/blob/v5/README.md
+// Message is what's sent for a GitHub event.
+type Message struct {
+	// Text is the formatted message. It's empty if the event was dropped on
+	// purpose.
+	Text string
+	// Event is the name of the GitHub event, as in "issues".
+	Event string
+	// Repository is the full name of the repository of the event, as in
+	// "owner/repo", if it has one.
+	Repository string
+	// Sender is the login of who triggered the event.
+	Sender string
+	// URL is the page of what the event is about, such as the comment or
+	// the issue, or the one of the repository.
+	URL string
+	// ImageURL is the first image in the body of the issue or pull request,
+	// if Options.ExtractImages is set.
+	ImageURL string
+	// Status is set for the status events, so that the ones of the same
+	// commit can be summed up in a single message.
+	Status *Status
+	// Buttons are the links shown under the message, if
+	// Options.InlineButtons is set.
+	Buttons []Button
+	// Failed is true if the event reports a failure, such as a failed status
+	// or page build.
+	Failed bool
+	// Assignee is the login of who a pull request was assigned to, or whose
+	// review was requested, so that they can be pinged in their own chat.
+	Assignee string
+	// Severity is how urgent the message is: SeverityInfo, SeverityWarning
+	// or SeverityCritical, as set by Options.Severities.
+	Severity string
+	// PullRequest is the URL of the pull request of the pull_request and
+	// pull_request_review events, and Merged is true once it's merged.
+	PullRequest string
+	Merged      bool
+	// Review is the state of the review of the pull_request_review events,
+	// as in "approved" or "dismissed", and Reviewer is who wrote it. They
+	// allow counting the approvals of the pull requests, which the payloads
+	// don't have.
+	Review   string
+	Reviewer string
+}
+
+// Button is a link shown under the message, if Options.InlineButtons is set.
+type Button struct {
+	Text string
+	URL  string
+}
+
+// Security returns true if the message is about a security alert.
+func (m Message) Security() bool {
+	return m.Event == "repository_vulnerability_alert" || m.Event == "security_advisory"
+}
+
+// Compliance returns true if the message is about the rules of a repository,
+// such as the protection of its branches.
+func (m Message) Compliance() bool {
+	return m.Event == "branch_protection_rule"
+}
+
+// Infra returns true if the message is about the runners of the workflows.
+func (m Message) Infra() bool {
+	return m.Event == "workflow_job"
+}
+
+// Package returns true if the message is about GitHub Packages.
+func (m Message) Package() bool {
+	return m.Event == "package" || m.Event == "registry_package"
+}
+
+// GetMessage parses the GitHub event received in the request, checking its
+// signature with the secret, and returns the text of the message to send. An
+// empty message means that the event was dropped on purpose.
 func GetMessage(r *http.Request, secret string) (string, error) {
-	// Handling the Github event
-	hook, _ := github.New(github.Options.Secret(secret))
-	payload, err := hook.Parse(r,
-		// Comment events
-		github.CommitCommentEvent,
-		github.IssueCommentEvent,
-		github.PullRequestReviewCommentEvent,
-		// Events that have CRUD-like actions
-		github.PullRequestReviewEvent,
-		github.PullRequestEvent,
-		github.IssuesEvent,
-		// Misc
-		github.StatusEvent,
-		github.PingEvent)
+	return GetMessageWith(r, Options{Secret: secret})
+}
+
+// GetMessageWith is GetMessage, as set by the options.
+func GetMessageWith(r *http.Request, o Options) (string, error) {
+	message, err := Parse(r, o)
+	return message.Text, err
+}
 
+// Parse parses the GitHub event received in the request and returns the
+// message to send. Its errors are an *Error, whose kind tells why the event
+// wasn't sent.
+func Parse(r *http.Request, o Options) (Message, error) {
+	message, err := parse(r, o)
+	return message, classify(err)
+}
+
+// parse parses the GitHub event received in the request.
+// Taken from: https://github.com/go-playground/webhooks/blob/v5/README.md
+func parse(r *http.Request, o Options) (Message, error) {
+	// The signature is checked by readPayload, since form-encoded deliveries
+	// need to be unwrapped before the library can parse them.
+	secret := o.Secret
+	if o.skipsSignature(r) {
+		secret = ""
+	}
+	body, err := readPayload(r, secret)
 	if err != nil {
-		return "", err
+		return Message{}, err
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+
+	// The events the webhooks library doesn't know are parsed by us, out of
+	// the raw payload.
+	raw, isRaw := rawEvents[event]
+	if !isRaw {
+		raw, isRaw = rawActions[event+":"+actionOf(body)]
+	}
+	if !isRaw && (event == "issues" || event == "pull_request") && labelsOnlyEdit(body) {
+		raw, isRaw = formatLabelsEdited, true
+	}
+
+	var payload interface{}
+	if !isRaw {
+		// Handling the Github event
+		hook, _ := github.New()
+		payload, err = hook.Parse(withPayload(r, body),
+			// Comment events
+			github.CommitCommentEvent,
+			github.IssueCommentEvent,
+			github.PullRequestReviewCommentEvent,
+			// Events that have CRUD-like actions
+			github.PullRequestReviewEvent,
+			github.PullRequestEvent,
+			github.IssuesEvent,
+			// Misc
+			github.StatusEvent,
+			github.PageBuildEvent)
+
+		// The events nobody parses can still be sent with the default
+		// template.
+		if err == github.ErrEventNotFound && o.DefaultEventTemplate != "" {
+			isRaw, err = true, nil
+			raw = func(payload []byte, o Options) (string, error) {
+				return formatDefault(event, payload, o)
+			}
+		}
+		if err != nil {
+			return Message{}, err
+		}
+	}
+
+	sender := senderOf(body)
+	if o.skipSender(sender) {
+		return Message{}, nil
+	}
+	if err := o.notAllowedSender(sender); err != nil {
+		return Message{}, err
+	}
+	if err := o.notAllowedRepository(body); err != nil {
+		return Message{}, err
+	}
+	if err := o.notAllowedAge(body); err != nil {
+		return Message{}, err
+	}
+
+	// The events that must always be sent skip every other filter.
+	o.force = o.alwaysNotify(event, actionOf(body))
+	o.repo = repositoryOf(body).FullName
+	if err := o.allow(o.notAllowedEvent(event)); err != nil {
+		return Message{}, err
+	}
+
+	var text string
+	if isRaw {
+		text, err = raw(body, o)
+	} else {
+		text, err = format(payload, o)
+	}
+	if err != nil || text == "" {
+		return Message{}, err
+	}
+	text = o.withBadge(body, text)
+	// The bodies are redacted before they're cut, and the rest of the message,
+	// such as the titles, once it's rendered.
+	text = o.redact(text)
+
+	repo := repositoryOf(body)
+	if template := o.templateFor(repo.FullName, event, actionOf(body)); template != "" {
+		if text, err = fill(template, event, body, text, o); err != nil {
+			return Message{}, err
+		}
+	}
+	text = o.withRepository(repo, text)
+	text = o.shortenLinks(event, text)
+
+	message := Message{Text: text, Event: event, Repository: repo.FullName, Sender: sender, URL: urlOf(body)}
+	switch p := payload.(type) {
+	case github.StatusPayload:
+		status := newStatus(p)
+		message.Status = &status
+		message.Failed = status.Failed()
+	case github.PageBuildPayload:
+		message.Failed = PageBuild{Status: p.Build.Status}.Failed()
+	case github.PullRequestPayload:
+		if o.InlineButtons {
+			message.Buttons = pullRequestButtons(p.PullRequest.HTMLURL)
+		}
+		if p.Action == "assigned" && p.Assignee != nil {
+			message.Assignee = p.Assignee.Login
+		}
+		message.PullRequest = p.PullRequest.HTMLURL
+		message.Merged = p.Action == "closed" && p.PullRequest.Merged
+	}
+	if event == "workflow_job" {
+		message.Failed = workflowJobFailed(body)
+	}
+	if event == "check_run" {
+		message.Failed = checkRunFailed(body)
+	}
+	if event == "pull_request" && actionOf(body) == "review_requested" {
+		message.Assignee = requestedReviewerOf(body)
+	}
+	if event == "pull_request_review" {
+		message.PullRequest, message.Review, message.Reviewer = reviewOf(body)
+	}
+	message.Severity = o.severityOf(event, actionOf(body), message.Failed)
+	if o.ExtractImages {
+		message.ImageURL = firstImage(bodyOf(event, body))
+	}
+	return message, nil
+}
+
+// pullRequestButtons links to the pull request and to its diff.
+func pullRequestButtons(htmlURL string) []Button {
+	return []Button{
+		{Text: "Open PR", URL: htmlURL},
+		{Text: "View Diff", URL: strings.TrimSuffix(htmlURL, "/") + "/files"},
 	}
+}
 
+// format returns the text of the message for the parsed payload.
+func format(payload interface{}, o Options) (string, error) {
 	// NOTES:
 	// - The cases can't fallthrough when they belong to a switch over types.
 	// - I'm trying to pass objects of a well defined struct to make the parsing functions smaller,
@@ -38,32 +257,48 @@ func GetMessage(r *http.Request, secret string) (string, error) {
 	// Comment events
 	case github.CommitCommentPayload:
 		p := payload.(github.CommitCommentPayload)
-		sender := Sender{Login: p.Sender.Login, HTMLURL: p.Sender.HTMLURL}
-		comment := Comment{Body: p.Comment.Body, HTMLURL: p.Comment.HTMLURL}
+		sender := o.sender(p.Sender.Login, p.Sender.HTMLURL)
+		comment, err := newComment(p.Action, p.Comment.Body, p.Comment.HTMLURL, o)
+		if err != nil {
+			return "", err
+		}
+		if p.Comment.Path != nil {
+			comment.Path = *p.Comment.Path
+		}
+		if p.Comment.Line != nil {
+			comment.Line = *p.Comment.Line
+		}
 
 		return comment.Format("commit", sender), nil
 
 	case github.IssueCommentPayload:
 		p := payload.(github.IssueCommentPayload)
-		sender := Sender{Login: p.Sender.Login, HTMLURL: p.Sender.HTMLURL}
-		comment := Comment{Body: p.Comment.Body, HTMLURL: p.Comment.HTMLURL}
+		sender := o.sender(p.Sender.Login, p.Sender.HTMLURL)
+		comment, err := newComment(p.Action, p.Comment.Body, p.Comment.HTMLURL, o)
+		if err != nil {
+			return "", err
+		}
 
 		return comment.Format("issue", sender), nil
 
 	case github.PullRequestReviewCommentPayload:
 		p := payload.(github.PullRequestReviewCommentPayload)
-		sender := Sender{Login: p.Sender.Login, HTMLURL: p.Sender.HTMLURL}
-		comment := Comment{Body: p.Comment.Body, HTMLURL: p.Comment.HTMLURL}
+		sender := o.sender(p.Sender.Login, p.Sender.HTMLURL)
+		comment, err := newComment(p.Action, p.Comment.Body, p.Comment.HTMLURL, o)
+		if err != nil {
+			return "", err
+		}
+		comment.Path, comment.DiffHunk = p.Comment.Path, p.Comment.DiffHunk
 
 		return comment.Format("pull request", sender), nil
 
 		// Events that have CRUD-like actions
 	case github.PullRequestReviewPayload:
 		p := payload.(github.PullRequestReviewPayload)
-		sender := Sender{Login: p.Sender.Login, HTMLURL: p.Sender.HTMLURL}
-		content := Content{Action: p.Action, Title: p.PullRequest.Title, HTMLURL: p.PullRequest.HTMLURL, Body: p.Review.Body}
+		sender := o.sender(p.Sender.Login, p.Sender.HTMLURL)
+		content := Content{Action: p.Action, DetailsLabel: o.detailsLabel(), Title: p.PullRequest.Title, HTMLURL: p.PullRequest.HTMLURL, Body: o.body(p.Review.Body)}
 
-		if err := content.NotAllowed(); err != nil {
+		if err := o.allow(content.NotAllowed(o)); err != nil {
 			return "", err
 		}
 
@@ -71,41 +306,96 @@ func GetMessage(r *http.Request, secret string) (string, error) {
 
 	case github.PullRequestPayload:
 		p := payload.(github.PullRequestPayload)
-		sender := Sender{Login: p.Sender.Login, HTMLURL: p.Sender.HTMLURL}
-		body := fmt.Sprintf("Additions: %d Deletions: %d", p.PullRequest.Additions, p.PullRequest.Deletions)
-		content := Content{Action: p.Action, Title: p.PullRequest.Title, HTMLURL: p.PullRequest.HTMLURL, Body: body}
+		sender := o.sender(p.Sender.Login, p.Sender.HTMLURL)
+		content := Content{Action: p.Action, DetailsLabel: o.detailsLabel(), Title: p.PullRequest.Title, HTMLURL: p.PullRequest.HTMLURL}
+		if contains(DiffStatsActions, p.Action) {
+			content.Body = o.diffStats(p.PullRequest.Additions, p.PullRequest.Deletions)
+		}
+		for _, label := range p.PullRequest.Labels {
+			content.Labels = append(content.Labels, label.Name)
+		}
 
-		if err := content.NotAllowed(); err != nil {
+		if err := o.allow(content.NotAllowed(o)); err != nil {
+			return "", err
+		}
+		if err := o.allow(o.notAllowedPullRequest(p.Action, p.PullRequest.Merged)); err != nil {
 			return "", err
 		}
+		if text, ok := assignment(content, sender, p.Assignee, "pull request", p.Number, o); ok {
+			return text, nil
+		}
+		if text, ok := autoMerge(content, sender, p.Number); ok {
+			return text, nil
+		}
+		if text, ok := draft(content, sender, p.Number); ok {
+			return text, nil
+		}
+		if text, ok := synchronize(content, sender, p.Number, p.PullRequest.Head.Sha); ok {
+			return text, nil
+		}
 
-		return content.Format("pull request", sender), nil
+		return o.withMarker(content, content.Format("pull request", sender)), nil
 
 	case github.IssuesPayload:
 		p := payload.(github.IssuesPayload)
-		sender := Sender{Login: p.Sender.Login, HTMLURL: p.Sender.HTMLURL}
-		content := Content{Action: p.Action, Title: p.Issue.Title, HTMLURL: p.Issue.HTMLURL}
+		sender := o.sender(p.Sender.Login, p.Sender.HTMLURL)
+		content := Content{Action: p.Action, DetailsLabel: o.detailsLabel(), Title: p.Issue.Title, HTMLURL: p.Issue.HTMLURL}
+		for _, label := range p.Issue.Labels {
+			content.Labels = append(content.Labels, label.Name)
+		}
 
-		if err := content.NotAllowed(); err != nil {
+		if err := o.allow(content.NotAllowed(o)); err != nil {
 			return "", err
 		}
+		if text, ok := assignment(content, sender, p.Assignee, "issue", p.Issue.Number, o); ok {
+			return text, nil
+		}
+		if text, ok := pin(content, sender, p.Issue.Number); ok {
+			return text, nil
+		}
+		if text, ok := closed(content, sender, o.sender(p.Issue.User.Login, p.Issue.User.HTMLURL), p.Issue.Number); ok {
+			return text, nil
+		}
 
-		return content.Format("issue", sender), nil
+		return o.withMarker(content, content.Format("issue", sender)), nil
 
 		// Status are events triggered by commits
 	case github.StatusPayload:
 		p := payload.(github.StatusPayload)
-		sender := Sender{Login: p.Sender.Login, HTMLURL: p.Sender.HTMLURL}
-		status := Status{State: p.State, Message: p.Commit.Commit.Message, HTMLURL: p.Commit.HTMLURL}
+		status := newStatus(p)
+		status.Message = o.clampBody(o.redact(status.Message))
+		sender := o.statusPeople(p, &status)
 
-		if err := status.NotAllowed(); err != nil {
+		if err := o.allow(status.NotAllowed(o)); err != nil {
 			return "", err
 		}
 
-		return status.Format(sender), nil
-		// Ping is simply so that we can run a minimal test.
-	case github.PingPayload:
-		return "ping", nil
+		message := status.Format(sender, o.statusEmoji())
+		if status.Failed() {
+			message = o.withMentions("status", message)
+		}
+
+		return message, nil
+
+		// Builds of the GitHub Pages sites
+	case github.PageBuildPayload:
+		p := payload.(github.PageBuildPayload)
+		pusher := o.sender(p.Build.Pusher.Login, p.Build.Pusher.HTMLURL)
+		build := PageBuild{Status: p.Build.Status, Repo: p.Repository.FullName, RepoURL: p.Repository.HTMLURL}
+		if p.Build.Error.Message != nil {
+			build.Error = *p.Build.Error.Message
+		}
+
+		if err := o.allow(build.NotAllowed()); err != nil {
+			return "", err
+		}
+
+		message := build.Format(pusher, o.statusEmoji())
+		if build.Failed() {
+			message = o.withMentions("page_build", message)
+		}
+
+		return message, nil
 	}
 
 	return "", nil
@@ -0,0 +1,41 @@
+package gh
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// markdownImage matches the Markdown images, as in ![alt](url "title").
+var markdownImage = regexp.MustCompile(`!\[[^\]]*\]\((\S+?)(?:\s+"[^"]*")?\)`)
+
+// firstImage returns the URL of the first Markdown image in the text, or an
+// empty string if there's none.
+func firstImage(text string) string {
+	match := markdownImage.FindStringSubmatch(text)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// bodyOf reads the body of the issue or the pull request out of the raw
+// payload of the issues and pull_request events.
+func bodyOf(event string, payload []byte) string {
+	if event != "issues" && event != "pull_request" {
+		return ""
+	}
+
+	var p struct {
+		Issue struct {
+			Body string `json:"body"`
+		} `json:"issue"`
+		PullRequest struct {
+			Body string `json:"body"`
+		} `json:"pull_request"`
+	}
+	json.Unmarshal(payload, &p)
+	if p.Issue.Body != "" {
+		return p.Issue.Body
+	}
+	return p.PullRequest.Body
+}
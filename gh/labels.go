@@ -0,0 +1,101 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// rawLabel is the part of the labels we use.
+type rawLabel struct {
+	Name string `json:"name"`
+}
+
+// labeledIssue is an issue, or a pull request, with its labels.
+type labeledIssue struct {
+	rawIssue
+	Labels []rawLabel `json:"labels"`
+}
+
+// labelsEditedPayload holds the fields we use of the edited actions of the
+// issues and the pull requests whose only change is their labels. The
+// webhooks library only reads the changes of the title and the body.
+type labelsEditedPayload struct {
+	Action  string                     `json:"action"`
+	Changes map[string]json.RawMessage `json:"changes"`
+	Issue   *labeledIssue              `json:"issue"`
+	PR      *labeledIssue              `json:"pull_request"`
+	Sender  rawUser                    `json:"sender"`
+}
+
+// labelsOnlyEdit returns true if the payload is of an edit that only changed
+// the labels. GitHub usually reports those as labeled and unlabeled actions,
+// but the edits can carry them in their changes too.
+func labelsOnlyEdit(payload []byte) bool {
+	var p labelsEditedPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return false
+	}
+	_, ok := p.Changes["labels"]
+	return p.Action == "edited" && len(p.Changes) == 1 && ok
+}
+
+// formatLabelsEdited reports the edits that only changed the labels, as in "X
+// changed the labels of issue #2 (added [bug], removed [wontfix])", instead of
+// as any other edit. They're ignored unless edited is in the EnabledActions.
+func formatLabelsEdited(payload []byte, o Options) (string, error) {
+	var p labelsEditedPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	if err := o.allow(Content{Action: p.Action}.NotAllowed(o)); err != nil {
+		return "", err
+	}
+	var changes struct {
+		From []rawLabel `json:"from"`
+	}
+	if err := json.Unmarshal(p.Changes["labels"], &changes); err != nil {
+		return "", err
+	}
+
+	issue, kind := p.Issue, "issue"
+	if p.PR != nil {
+		issue, kind = p.PR, "PR"
+	}
+	if issue == nil {
+		return "", fmt.Errorf("gh: missing issue of the labels edit")
+	}
+
+	var diff []string
+	if added := labelsDiff(issue.Labels, changes.From); added != "" {
+		diff = append(diff, "added "+added)
+	}
+	if removed := labelsDiff(changes.From, issue.Labels); removed != "" {
+		diff = append(diff, "removed "+removed)
+	}
+	var details string
+	if len(diff) > 0 {
+		details = " (" + strings.Join(diff, ", ") + ")"
+	}
+
+	return fmt.Sprintf(
+		"%s changed the labels of %s #%d%s: %s %s",
+		p.Sender.sender(o).Link(), kind, issue.Number, details, issue.Title, issue.HTMLURL,
+	), nil
+}
+
+// labelsDiff returns the labels that aren't among the others, as a bracketed
+// list, as in "[bug][p1]".
+func labelsDiff(labels []rawLabel, others []rawLabel) string {
+	var diff strings.Builder
+	for _, label := range labels {
+		found := false
+		for _, other := range others {
+			found = found || other.Name == label.Name
+		}
+		if !found {
+			diff.WriteString(EscapeMarkdown("[" + label.Name + "]"))
+		}
+	}
+	return diff.String()
+}
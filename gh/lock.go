@@ -0,0 +1,41 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// lockPayload holds the fields we use of the locked and unlocked actions of the
+// issues and the pull requests. The webhooks library drops the lock reason.
+type lockPayload struct {
+	Action      string   `json:"action"`
+	Issue       rawIssue `json:"issue"`
+	PullRequest rawIssue `json:"pull_request"`
+	Sender      rawUser  `json:"sender"`
+}
+
+// formatLock reports the conversations locked and unlocked by the moderators,
+// as in "X locked the conversation on issue #2 (reason: too heated)".
+func formatLock(payload []byte, o Options) (string, error) {
+	var p lockPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	if err := o.allow(Content{Action: p.Action}.NotAllowed(o)); err != nil {
+		return "", err
+	}
+
+	kind, issue := "issue", p.Issue
+	if p.PullRequest.HTMLURL != "" {
+		kind, issue = "pull request", p.PullRequest
+	}
+	var reason string
+	if p.Action == "locked" && issue.ActiveLockReason != "" {
+		reason = fmt.Sprintf(" (reason: %s)", EscapeMarkdown(issue.ActiveLockReason))
+	}
+
+	return fmt.Sprintf(
+		"%s %s the conversation on %s #%d%s: %s %s",
+		p.Sender.sender(o).Link(), p.Action, kind, issue.Number, reason, issue.Title, issue.HTMLURL,
+	), nil
+}
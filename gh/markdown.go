@@ -0,0 +1,17 @@
+package gh
+
+import "strings"
+
+// markdownEscaper escapes the characters that have a meaning in Telegram's
+// Markdown, which is the parse mode the messages are sent with.
+var markdownEscaper = strings.NewReplacer(
+	"_", "\\_",
+	"*", "\\*",
+	"`", "\\`",
+	"[", "\\[",
+)
+
+// EscapeMarkdown makes the text show up as it is once sent with Markdown.
+func EscapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
@@ -0,0 +1,59 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// rawMilestone is the part of the milestones we use.
+type rawMilestone struct {
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+}
+
+// milestonePayload holds the fields we use of the milestoned and demilestoned
+// actions of the issues. The webhooks library drops the milestone of the
+// payload, which is the only one left once the issue is demilestoned.
+type milestonePayload struct {
+	Action    string        `json:"action"`
+	Milestone *rawMilestone `json:"milestone"`
+	Issue     struct {
+		rawIssue
+		Milestone *rawMilestone `json:"milestone"`
+	} `json:"issue"`
+	Sender rawUser `json:"sender"`
+}
+
+// formatMilestone reports the issues added to a milestone, or removed from it,
+// as in "X added issue #2 to milestone v1.0". They're ignored unless they're in
+// the EnabledActions.
+func formatMilestone(payload []byte, o Options) (string, error) {
+	var p milestonePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	if err := o.allow(Content{Action: p.Action}.NotAllowed(o)); err != nil {
+		return "", err
+	}
+
+	milestone := p.Milestone
+	if milestone == nil {
+		milestone = p.Issue.Milestone
+	}
+	name := "a milestone"
+	if milestone != nil && milestone.Title != "" {
+		name = "milestone " + EscapeMarkdown(milestone.Title)
+		if milestone.HTMLURL != "" {
+			name = fmt.Sprintf("milestone [%s](%s)", EscapeMarkdown(milestone.Title), milestone.HTMLURL)
+		}
+	}
+
+	verb, preposition := "added", "to"
+	if p.Action == "demilestoned" {
+		verb, preposition = "removed", "from"
+	}
+	return fmt.Sprintf(
+		"%s %s issue #%d %s %s: %s %s",
+		p.Sender.sender(o).Link(), verb, p.Issue.Number, preposition, name, p.Issue.Title, p.Issue.HTMLURL,
+	), nil
+}
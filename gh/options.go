@@ -0,0 +1,245 @@
+package gh
+
+import (
+	"net"
+	"time"
+)
+
+// Options tweak how the GitHub events are parsed and formatted. The zero value
+// is the default behavior.
+type Options struct {
+	// Secret is the GitHub Webhook secret used to check the signatures.
+	Secret string
+	// AcceptUnsigned skips checking the signatures of the requests coming
+	// from the TrustedNetworks, which is meant for internal deployments
+	// where the secret is being rotated. Both must be set.
+	AcceptUnsigned  bool
+	TrustedNetworks []*net.IPNet
+	// SelfLogin is the GitHub login of the account the bot acts with, if
+	// any. Events sent by it are dropped to avoid feedback loops.
+	SelfLogin string
+	// AllowedSenders are the only logins whose events are sent, if it's
+	// set. SelfLogin is dropped anyway.
+	AllowedSenders []string
+	// PublicOnly drops the events of the private repositories, and the
+	// ones that don't say whether their repository is private, so nothing
+	// private can leak. Not even AlwaysNotify skips it.
+	PublicOnly bool
+	// MaxEventAge drops the events that happened longer ago than it, out of
+	// the timestamps in their payloads, such as the ones redelivered after a
+	// downtime. The events without a timestamp are sent. Not even
+	// AlwaysNotify skips it.
+	MaxEventAge time.Duration
+	// StatusEmoji maps the states of the status events to how they're
+	// presented. Its entries override the ones in DefaultStatusEmoji.
+	StatusEmoji map[string]string
+	// StatusStates are the states of the status events that are sent. Every
+	// state but "pending" is sent if it's empty.
+	StatusStates []string
+	// StatusPeople sets who the statuses are shown to be by: "author" (the
+	// default), "committer" or "both", out of the commit.
+	StatusPeople string
+	// AlertMentions maps kinds of events (such as "status" or "page_build")
+	// to the Telegram @usernames to mention when they fail.
+	AlertMentions map[string]string
+	// Severities maps events, as "event" or as "event:action" (the state of
+	// the statuses, and the status of the page builds, count as their
+	// action), to the severity of their messages. The failures are
+	// critical, and the rest are info, if they're not in it.
+	Severities map[string]string
+	// ReopenedMarker is put before the messages of reopened issues and pull
+	// requests, to make them stand out. For example: "🔄".
+	ReopenedMarker string
+	// PREvents set to "merged" drops every pull request event except the
+	// ones of pull requests being merged.
+	PREvents string
+	// PRSize shows the size category of the pull requests instead of their
+	// additions and deletions, as in "size: M (+120 −30)". The categories
+	// start at the PRSizeThresholds, or at the DefaultPRSizeThresholds
+	// unless there are four of them.
+	PRSize           bool
+	PRSizeThresholds []int
+	// EnabledActions are actions of DefaultIgnoredActions that must be sent
+	// anyway.
+	EnabledActions []string
+	// IgnoredActions are dropped on top of DefaultIgnoredActions.
+	IgnoredActions []string
+	// EnabledEvents are events of DefaultDisabledEvents that must be sent
+	// anyway.
+	EnabledEvents []string
+	// ExtractImages looks for the first image in the body of the issues and
+	// pull requests, so it can be sent along with the message.
+	ExtractImages bool
+	// AlwaysNotify lists the events that are sent no matter what the other
+	// filters say, either as "event" or as "event:action". The action of the
+	// status events is their state, as in "status:failure". Only SelfLogin,
+	// AllowedSenders and PublicOnly are checked before them.
+	AlwaysNotify []string
+	// RepoDisplay sets how the repository is shown before the messages:
+	// "full" for "owner/repo", "short" for "repo", and "none" (the default)
+	// to leave it out.
+	RepoDisplay string
+	// InlineButtons adds buttons linking to what the message is about, such
+	// as "Open PR" and "View Diff" for the pull requests.
+	InlineButtons bool
+	// SenderFormat sets how the senders are shown: "link" (the default),
+	// "plain" or "mention". See Sender.Format.
+	SenderFormat string
+	// Badges are the author associations, as in "FIRST_TIME_CONTRIBUTOR",
+	// badged after the senders of the comments, the reviews, the issues and
+	// the pull requests they wrote. They're the DefaultBadges if it's empty,
+	// and nothing is badged if it's "none".
+	Badges []string
+	// MaxBodyLen is the most characters shown of the bodies of the comments,
+	// the reviews and the commit messages. There's no limit if it's zero.
+	MaxBodyLen int
+	// MinCommentLen drops the comments shorter than it, once trimmed, such
+	// as "lgtm" or "+1". Every comment is sent if it's zero.
+	MinCommentLen int
+	// DeletedPreview is how many characters of the deleted comments are
+	// shown. They're not shown if it's zero.
+	DeletedPreview int
+	// Redact replaces the secrets that match the DefaultRedactPatterns in
+	// the messages: the bodies of the comments, the reviews and the commit
+	// messages, the titles, and everything else.
+	Redact bool
+	// RedactPatterns are regular expressions whose matches are replaced in
+	// the bodies too, with or without Redact.
+	RedactPatterns []string
+	// QuoteBodies renders the bodies of the comments and the reviews as
+	// quotes.
+	QuoteBodies bool
+	// LinkReferences links the references to the issues and pull requests in
+	// the bodies of the comments and the reviews, as in "#2" or
+	// "owner/repo#2", to GitHub.
+	LinkReferences bool
+	// Shortener shortens the links to GitHub in the messages of the
+	// ShortenEvents, or of every event if there are none.
+	Shortener     Shortener `json:"-"`
+	ShortenEvents []string
+	// DetailsLabel goes before the bodies of the issues, pull requests and
+	// reviews, instead of DefaultDetailsLabel. If it's "none", the bodies go
+	// on their own line without a label.
+	DetailsLabel string
+	// DefaultEventTemplate is used for the events that aren't parsed
+	// otherwise, which are dropped with an error if it's empty. Its {event},
+	// {action}, {sender} and {repo} placeholders are replaced with the ones of
+	// the event. For example: "{sender} {action} a {event} in {repo}".
+	DefaultEventTemplate string
+	// Templates map the kinds of events, either "event" or "event:action",
+	// to the template of their messages. Their {event}, {action}, {sender},
+	// {repo} and {message} placeholders are replaced with the ones of the
+	// event, {message} being the message telebot sends by default. For
+	// example: {"issues:opened": "🐛 {message}"}.
+	Templates map[string]string
+	// RepoTemplates are the Templates of each repository, by their full
+	// name, as in "owner/repo". They're picked over the Templates.
+	RepoTemplates map[string]map[string]string
+
+	// force is set while formatting an AlwaysNotify event.
+	force bool
+	// repo is the full name of the repository of the event being formatted.
+	repo string
+}
+
+// ignoresAction returns true if the events with the given action must be
+// dropped.
+func (o Options) ignoresAction(action string) bool {
+	if contains(o.IgnoredActions, action) {
+		return true
+	}
+	return contains(DefaultIgnoredActions, action) && !contains(o.EnabledActions, action)
+}
+
+// notAllowedEvent returns an error if the event is one of the disabled ones.
+func (o Options) notAllowedEvent(event string) error {
+	if contains(DefaultDisabledEvents, event) && !contains(o.EnabledEvents, event) {
+		return filtered("gh: not allowed event, %s", event)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// statusEmoji returns the default status presentation merged with the
+// configured one.
+func (o Options) statusEmoji() map[string]string {
+	emoji := map[string]string{}
+	for state, e := range DefaultStatusEmoji {
+		emoji[state] = e
+	}
+	for state, e := range o.StatusEmoji {
+		emoji[state] = e
+	}
+	return emoji
+}
+
+// withMentions appends the mentions configured for the kind of event to the
+// message. The usernames are left as they are, so Telegram notifies them.
+func (o Options) withMentions(kind string, message string) string {
+	mentions := o.AlertMentions[kind]
+	if mentions == "" {
+		return message
+	}
+	return message + "\n\n" + mentions
+}
+
+// withMarker puts the reopened marker before the message if the content was
+// reopened.
+func (o Options) withMarker(c Content, message string) string {
+	if o.ReopenedMarker == "" || c.Action != "reopened" {
+		return message
+	}
+	return o.ReopenedMarker + " " + message
+}
+
+// body returns the body of a comment or a review as it's shown: redacted,
+// clamped, with its references linked if LinkReferences is set, and quoted if
+// QuoteBodies is set. It's clamped before the rest, so the markup is never cut.
+func (o Options) body(body string) string {
+	body = o.clampBody(o.redact(body))
+	if o.LinkReferences {
+		body = linkReferences(body, o.repo)
+	}
+	if o.QuoteBodies {
+		body = quote(body)
+	}
+	return body
+}
+
+// clampBody cuts the body down to MaxBodyLen characters, marking where it was
+// cut with an ellipsis.
+func (o Options) clampBody(body string) string {
+	if o.MaxBodyLen <= 0 {
+		return body
+	}
+	return clamp(body, o.MaxBodyLen)
+}
+
+// clamp cuts the text down to max characters, marking where it was cut with an
+// ellipsis.
+func clamp(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
+
+// detailsLabel returns the label that goes before the bodies.
+func (o Options) detailsLabel() string {
+	switch o.DetailsLabel {
+	case "":
+		return DefaultDetailsLabel
+	case "none":
+		return ""
+	}
+	return o.DetailsLabel
+}
@@ -0,0 +1,53 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PackageMarker starts every message of the GitHub Packages.
+const PackageMarker = "📦"
+
+// packagePayload holds the fields we use of the package and registry_package
+// events. They're the same event, the package one being the newer name.
+type packagePayload struct {
+	Action          string        `json:"action"`
+	Package         rawPackage    `json:"package"`
+	RegistryPackage rawPackage    `json:"registry_package"`
+	Repository      rawRepository `json:"repository"`
+	Sender          rawUser       `json:"sender"`
+}
+
+type rawPackage struct {
+	Name           string `json:"name"`
+	PackageVersion struct {
+		Version string `json:"version"`
+		HTMLURL string `json:"html_url"`
+	} `json:"package_version"`
+}
+
+// formatPackage reports the packages published to GitHub Packages.
+func formatPackage(payload []byte, o Options) (string, error) {
+	var p packagePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	pkg := p.Package
+	if pkg.Name == "" {
+		pkg = p.RegistryPackage
+	}
+
+	if p.Action != "published" {
+		return "", o.allow(filtered("gh: not allowed package action, %s", p.Action))
+	}
+
+	version := pkg.PackageVersion.Version
+	if pkg.PackageVersion.HTMLURL != "" {
+		version = fmt.Sprintf("[%s](%s)", version, pkg.PackageVersion.HTMLURL)
+	}
+
+	return fmt.Sprintf(
+		"%s %s published `%s` %s in %s",
+		PackageMarker, p.Sender.sender(o).Link(), pkg.Name, version, p.Repository.link(),
+	), nil
+}
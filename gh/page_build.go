@@ -0,0 +1,47 @@
+package gh
+
+import "fmt"
+
+// PageBuild handles the builds of the GitHub Pages sites.
+type PageBuild struct {
+	Status  string
+	Error   string
+	Repo    string
+	RepoURL string
+}
+
+// NotAllowed returns an error if the build hasn't finished yet.
+func (b PageBuild) NotAllowed() error {
+	switch b.Status {
+	case "built", "errored":
+		return nil
+	}
+
+	return filtered("gh: not allowed page build status, %s", b.Status)
+}
+
+// Failed returns true if the site couldn't be built.
+func (b PageBuild) Failed() bool {
+	return b.Status == "errored"
+}
+
+// Format returns a string with a formatted message to be sent for this build
+// with the passed pusher. The build status is presented with the same emoji
+// used for the status events.
+func (b PageBuild) Format(s Sender, emoji map[string]string) string {
+	if b.Failed() {
+		message := fmt.Sprintf(
+			"%s GitHub Pages failed to build [%s](%s) by %s",
+			emoji["failure"], b.Repo, b.RepoURL, s.Link(),
+		)
+		if b.Error != "" {
+			message += fmt.Sprintf(":\n%s", b.Error)
+		}
+		return message
+	}
+
+	return fmt.Sprintf(
+		"%s GitHub Pages built [%s](%s) by %s",
+		emoji["success"], b.Repo, b.RepoURL, s.Link(),
+	)
+}
@@ -0,0 +1,81 @@
+package gh
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gopkg.in/go-playground/webhooks.v5/github"
+)
+
+// readPayload reads the body of the request and checks its signature. GitHub
+// signs the body as it's sent, so the signature has to be verified before the
+// form-encoded deliveries are unwrapped.
+//
+// Webhooks configured with the "application/x-www-form-urlencoded" content type
+// send the JSON in a "payload" field. The returned payload is always the JSON.
+func readPayload(r *http.Request, secret string) ([]byte, error) {
+	if r.Method != http.MethodPost {
+		return nil, github.ErrInvalidHTTPMethod
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if secret != "" {
+		if err := verifySignature(body, r.Header.Get("X-Hub-Signature"), secret); err != nil {
+			return nil, err
+		}
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != "application/x-www-form-urlencoded" {
+		return body, nil
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, github.ErrParsingPayload
+	}
+
+	return []byte(form.Get("payload")), nil
+}
+
+// verifySignature checks that the X-Hub-Signature header matches the HMAC of
+// the body with the given secret.
+func verifySignature(body []byte, signature string, secret string) error {
+	if signature == "" {
+		return github.ErrMissingHubSignatureHeader
+	}
+	if !strings.HasPrefix(signature, "sha1=") {
+		return github.ErrHMACVerificationFailed
+	}
+
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(strings.TrimPrefix(signature, "sha1=")), []byte(expected)) {
+		return github.ErrHMACVerificationFailed
+	}
+
+	return nil
+}
+
+// withPayload returns a copy of the request with the given payload as its
+// body, so that it can be parsed by the webhooks library.
+func withPayload(r *http.Request, payload []byte) *http.Request {
+	parsed := r.WithContext(r.Context())
+	parsed.Body = ioutil.NopCloser(bytes.NewReader(payload))
+	parsed.ContentLength = int64(len(payload))
+	return parsed
+}
@@ -0,0 +1,13 @@
+package gh
+
+import "fmt"
+
+// pin returns the text of the pinned and unpinned actions of the issues, as in
+// "X pinned issue #2: Title url". It returns false for the other actions.
+func pin(c Content, s Sender, number int64) (string, bool) {
+	if c.Action != "pinned" && c.Action != "unpinned" {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s %s issue #%d: %s %s", s.Link(), c.Action, number, c.Title, c.HTMLURL), true
+}
@@ -0,0 +1,35 @@
+package gh
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// formatPing confirms that the Webhook is set up. The pings of the repository
+// Webhooks show the repository, so it's easy to check that it's the right one.
+func formatPing(payload []byte, o Options) (string, error) {
+	var p struct {
+		Repository struct {
+			rawRepository
+			Language    string `json:"language"`
+			Description string `json:"description"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+
+	repo := p.Repository
+	if repo.FullName == "" {
+		return "ping", nil
+	}
+
+	message := "ping from " + repo.link()
+	if repo.Language != "" {
+		message += " (" + EscapeMarkdown(repo.Language) + ")"
+	}
+	if description := strings.TrimSpace(repo.Description); description != "" {
+		message += ":\n\n" + EscapeMarkdown(description)
+	}
+	return message, nil
+}
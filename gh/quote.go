@@ -0,0 +1,19 @@
+package gh
+
+import "strings"
+
+// quote renders the body as a quote, with its lines starting with "> ".
+// Markdown, which the messages are written in, has no quotes, so the lines are
+// just marked there, and they're rendered as quotes for the chats with another
+// parse mode as they're sent (see tg.Convert).
+func quote(body string) string {
+	if body == "" {
+		return body
+	}
+
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,55 @@
+package gh
+
+// rawFormatter returns the text of the message for a raw payload.
+type rawFormatter func(payload []byte, o Options) (string, error)
+
+// rawEvents are the events that the webhooks library doesn't know, so we parse
+// them ourselves. Their payloads are decoded into structs that only hold the
+// fields we use.
+var rawEvents = map[string]rawFormatter{
+	"branch_protection_rule":         formatBranchProtectionRule,
+	"check_run":                      formatCheckRun,
+	"package":                        formatPackage,
+	"ping":                           formatPing,
+	"registry_package":               formatPackage,
+	"reaction":                       formatReaction,
+	"repository":                     formatRepository,
+	"repository_vulnerability_alert": formatVulnerabilityAlert,
+	"security_advisory":              formatSecurityAdvisory,
+	"workflow_job":                   formatWorkflowJob,
+}
+
+// rawActions are the actions whose payloads the webhooks library doesn't fully
+// read, by "event:action". They're parsed by us too.
+var rawActions = map[string]rawFormatter{
+	"issues:demilestoned":           formatMilestone,
+	"issues:locked":                 formatLock,
+	"issues:milestoned":             formatMilestone,
+	"issues:transferred":            formatIssueTransfer,
+	"issues:unlocked":               formatLock,
+	"pull_request:locked":           formatLock,
+	"pull_request:review_requested": formatReviewRequested,
+	"pull_request:unlocked":         formatLock,
+	"pull_request_review:dismissed": formatReviewDismissed,
+}
+
+// rawUser is the user that shows up in most of the raw payloads.
+type rawUser struct {
+	Login   string `json:"login"`
+	HTMLURL string `json:"html_url"`
+}
+
+func (u rawUser) sender(o Options) Sender {
+	return o.sender(u.Login, u.HTMLURL)
+}
+
+// rawRepository is the repository that shows up in most of the raw payloads.
+type rawRepository struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+}
+
+func (r rawRepository) link() string {
+	return "[" + r.FullName + "](" + r.HTMLURL + ")"
+}
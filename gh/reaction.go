@@ -0,0 +1,71 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// reactionEmoji maps the reactions GitHub has to their emoji.
+var reactionEmoji = map[string]string{
+	"+1":       "👍",
+	"-1":       "👎",
+	"laugh":    "😄",
+	"hooray":   "🎉",
+	"confused": "😕",
+	"heart":    "❤️",
+	"rocket":   "🚀",
+	"eyes":     "👀",
+}
+
+// reactionPayload holds the fields we use of the reaction event. The reaction
+// is either on a comment, an issue or a pull request.
+type reactionPayload struct {
+	Action   string `json:"action"`
+	Reaction struct {
+		Content string `json:"content"`
+	} `json:"reaction"`
+	Comment struct {
+		HTMLURL string `json:"html_url"`
+	} `json:"comment"`
+	Issue       rawIssue `json:"issue"`
+	PullRequest rawIssue `json:"pull_request"`
+	Sender      rawUser  `json:"sender"`
+}
+
+// rawIssue is the part of the issues and the pull requests we use.
+type rawIssue struct {
+	Number           int64  `json:"number"`
+	Title            string `json:"title"`
+	HTMLURL          string `json:"html_url"`
+	ActiveLockReason string `json:"active_lock_reason"`
+}
+
+// formatReaction reports the reactions to the issues, the pull requests and
+// their comments. It's one of the DefaultDisabledEvents, since it's noisy.
+func formatReaction(payload []byte, o Options) (string, error) {
+	var p reactionPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+
+	if p.Action != "created" {
+		return "", o.allow(filtered("gh: not allowed reaction action, %s", p.Action))
+	}
+
+	emoji, ok := reactionEmoji[p.Reaction.Content]
+	if !ok {
+		emoji = "`" + p.Reaction.Content + "`"
+	}
+
+	var target string
+	switch {
+	case p.Comment.HTMLURL != "":
+		target = "a comment " + p.Comment.HTMLURL
+	case p.Issue.HTMLURL != "":
+		target = "the issue: " + p.Issue.Title + " " + p.Issue.HTMLURL
+	default:
+		target = "the pull request: " + p.PullRequest.Title + " " + p.PullRequest.HTMLURL
+	}
+
+	return fmt.Sprintf("%s reacted %s to %s", p.Sender.sender(o).Link(), emoji, target), nil
+}
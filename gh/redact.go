@@ -0,0 +1,52 @@
+package gh
+
+import (
+	"regexp"
+	"sync"
+)
+
+// Redacted replaces the secrets found in the bodies.
+const Redacted = "***REDACTED***"
+
+// DefaultRedactPatterns match the secrets most often pasted by mistake, when
+// Options.Redact is set: AWS access keys, GitHub and Slack tokens, and private
+// keys, whole, up to their end or to the end of the body if it's cut.
+var DefaultRedactPatterns = []string{
+	`AKIA[0-9A-Z]{16}`,
+	`gh[pousr]_[A-Za-z0-9]{36,}`,
+	`github_pat_[A-Za-z0-9_]{22,}`,
+	`xox[abprs]-[A-Za-z0-9-]{10,}`,
+	`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(-----END [A-Z ]*PRIVATE KEY-----|\z)`,
+}
+
+// redactPatterns caches the compiled patterns, since the same ones are used
+// for every event.
+var redactPatterns sync.Map
+
+// compilePattern returns the compiled pattern, or nil if it's invalid.
+func compilePattern(pattern string) *regexp.Regexp {
+	if re, ok := redactPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	redactPatterns.Store(pattern, re)
+	return re
+}
+
+// redact replaces what matches the DefaultRedactPatterns, if Options.Redact is
+// set, and the RedactPatterns with Redacted. The invalid patterns are skipped.
+func (o Options) redact(body string) string {
+	patterns := o.RedactPatterns
+	if o.Redact {
+		patterns = append(append([]string{}, DefaultRedactPatterns...), patterns...)
+	}
+	for _, pattern := range patterns {
+		if re := compilePattern(pattern); re != nil {
+			body = re.ReplaceAllLiteralString(body, EscapeMarkdown(Redacted))
+		}
+	}
+	return body
+}
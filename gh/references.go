@@ -0,0 +1,48 @@
+package gh
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// codeSpan matches the code blocks and the inline codes, whose references are
+// left as they are.
+var codeSpan = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+
+// reference matches the references to the issues and pull requests, as in "#2"
+// or "Codertocat/Hello-World#2", at the start of a word.
+var reference = regexp.MustCompile(`(^|[\s(])([A-Za-z0-9][A-Za-z0-9-]*/[A-Za-z0-9._-]+)?#(\d+)\b`)
+
+// linkReferences links the references to the issues and pull requests in the
+// text to GitHub. The ones without a repository are in the given one, and they
+// aren't linked if it's unknown.
+func linkReferences(text string, repo string) string {
+	var linked strings.Builder
+	last := 0
+	for _, span := range codeSpan.FindAllStringIndex(text, -1) {
+		linked.WriteString(linkSpan(text[last:span[0]], repo))
+		linked.WriteString(text[span[0]:span[1]])
+		last = span[1]
+	}
+	linked.WriteString(linkSpan(text[last:], repo))
+	return linked.String()
+}
+
+// linkSpan links the references of a piece of text without code.
+func linkSpan(text string, repo string) string {
+	return reference.ReplaceAllStringFunc(text, func(match string) string {
+		parts := reference.FindStringSubmatch(match)
+		before, target, number := parts[1], parts[2], parts[3]
+		label := "#" + number
+		if target != "" {
+			label = target + label
+		} else {
+			target = repo
+		}
+		if target == "" {
+			return match
+		}
+		return fmt.Sprintf("%s[%s](https://github.com/%s/issues/%s)", before, EscapeMarkdown(label), target, number)
+	})
+}
@@ -0,0 +1,32 @@
+package gh
+
+import (
+	"encoding/json"
+)
+
+// repositoryOf reads the repository out of the raw payload. Most of the events
+// have one, but the ones without it return the zero value.
+func repositoryOf(payload []byte) rawRepository {
+	var p struct {
+		Repository rawRepository `json:"repository"`
+	}
+	json.Unmarshal(payload, &p)
+	return p.Repository
+}
+
+// withRepository puts the repository before the message, as set by
+// RepoDisplay: "full" shows "owner/repo", "short" only shows "repo", and
+// anything else shows nothing.
+func (o Options) withRepository(repo rawRepository, message string) string {
+	var name string
+	switch o.RepoDisplay {
+	case "full":
+		name = repo.FullName
+	case "short":
+		name = repo.Name
+	}
+	if name == "" {
+		return message
+	}
+	return "[" + name + "](" + repo.HTMLURL + ") " + message
+}
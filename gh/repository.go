@@ -0,0 +1,79 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// repositoryPayload holds the fields we use of the repository event.
+type repositoryPayload struct {
+	Action  string `json:"action"`
+	Changes struct {
+		Owner struct {
+			From struct {
+				User         *rawUser `json:"user"`
+				Organization *rawUser `json:"organization"`
+			} `json:"from"`
+		} `json:"owner"`
+	} `json:"changes"`
+	Repository struct {
+		rawRepository
+		Description   string `json:"description"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	Sender rawUser `json:"sender"`
+}
+
+// formatRepository reports the repositories created, and the ones transferred
+// to a new owner. The other actions are only sent with the
+// DefaultEventTemplate.
+func formatRepository(payload []byte, o Options) (string, error) {
+	var p repositoryPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+
+	if p.Action == "created" {
+		return formatRepositoryCreated(p, o)
+	}
+	if p.Action != "transferred" {
+		if o.DefaultEventTemplate != "" {
+			return formatDefault("repository", payload, o)
+		}
+		return "", o.allow(filtered("gh: not allowed repository action, %s", p.Action))
+	}
+	if err := o.allow(Content{Action: p.Action}.NotAllowed(o)); err != nil {
+		return "", err
+	}
+
+	var from string
+	if owner := p.Changes.Owner.From.User; owner != nil {
+		from = " from " + owner.sender(o).Link()
+	} else if owner := p.Changes.Owner.From.Organization; owner != nil {
+		from = " from " + owner.sender(o).Link()
+	}
+
+	return fmt.Sprintf(
+		"%s transferred the repository %s%s",
+		p.Sender.sender(o).Link(), p.Repository.link(), from,
+	), nil
+}
+
+// formatRepositoryCreated welcomes the new repositories, along with their
+// default branch and their description, if they have one.
+func formatRepositoryCreated(p repositoryPayload, o Options) (string, error) {
+	if err := o.allow(Content{Action: p.Action}.NotAllowed(o)); err != nil {
+		return "", err
+	}
+
+	repo := p.Repository
+	message := fmt.Sprintf("%s created a new repository, %s", p.Sender.sender(o).Link(), repo.link())
+	if repo.DefaultBranch != "" {
+		message += fmt.Sprintf(" (default branch `%s`)", repo.DefaultBranch)
+	}
+	if description := strings.TrimSpace(repo.Description); description != "" {
+		message += ":\n\n" + EscapeMarkdown(description)
+	}
+	return message, nil
+}
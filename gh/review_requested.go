@@ -0,0 +1,59 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// reviewRequestedPayload holds the fields we use of the review_requested action
+// of the pull requests. The webhooks library drops who the review was
+// requested from, which is either a user or a team.
+type reviewRequestedPayload struct {
+	Action            string   `json:"action"`
+	PullRequest       rawIssue `json:"pull_request"`
+	RequestedReviewer *rawUser `json:"requested_reviewer"`
+	RequestedTeam     *struct {
+		Name    string `json:"name"`
+		HTMLURL string `json:"html_url"`
+	} `json:"requested_team"`
+	Sender rawUser `json:"sender"`
+}
+
+// formatReviewRequested reports who was asked to review a pull request, as in
+// "X requested review from Y on PR #1: Title url", or "from team Z". It's
+// among the DefaultIgnoredActions, so it's only sent if it's enabled.
+func formatReviewRequested(payload []byte, o Options) (string, error) {
+	var p reviewRequestedPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	if err := o.allow(Content{Action: p.Action}.NotAllowed(o)); err != nil {
+		return "", err
+	}
+
+	reviewer := UnknownSender
+	if p.RequestedTeam != nil && p.RequestedTeam.Name != "" {
+		reviewer = "team " + EscapeMarkdown(p.RequestedTeam.Name)
+		if p.RequestedTeam.HTMLURL != "" {
+			reviewer = fmt.Sprintf("team [%s](%s)", EscapeMarkdown(p.RequestedTeam.Name), p.RequestedTeam.HTMLURL)
+		}
+	} else if p.RequestedReviewer != nil {
+		reviewer = p.RequestedReviewer.sender(o).Link()
+	}
+
+	return fmt.Sprintf(
+		"%s requested review from %s on PR #%d: %s %s",
+		p.Sender.sender(o).Link(), reviewer, p.PullRequest.Number, p.PullRequest.Title, p.PullRequest.HTMLURL,
+	), nil
+}
+
+// requestedReviewerOf reads the login of the user a review was requested from
+// out of the raw payload. It's empty for the teams.
+func requestedReviewerOf(payload []byte) string {
+	var p reviewRequestedPayload
+	json.Unmarshal(payload, &p)
+	if p.RequestedReviewer == nil {
+		return ""
+	}
+	return p.RequestedReviewer.Login
+}
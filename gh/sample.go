@@ -0,0 +1,76 @@
+package gh
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// sample is a payload GitHub could send, to try out the options without it.
+type sample struct {
+	Event   string
+	Payload string
+}
+
+// The parts most of the samples share.
+const (
+	sampleSender     = `"sender": {"login": "Codertocat", "html_url": "https://github.com/Codertocat"}`
+	sampleRepository = `"repository": {"name": "Hello-World", "full_name": "Codertocat/Hello-World", "html_url": "https://github.com/Codertocat/Hello-World", "private": false, "owner": {"login": "Codertocat"}}`
+	sampleIssue      = `"issue": {"number": 2, "title": "Spelling error in the README file", "html_url": "https://github.com/Codertocat/Hello-World/issues/2", "body": "It looks like you accidently spelled 'commit' with two 't's.", "labels": [], "user": {"login": "Codertocat"}}`
+	samplePR         = `"number": 1, "pull_request": {"number": 1, "title": "Update the README with new information", "html_url": "https://github.com/Codertocat/Hello-World/pull/1", "additions": 1, "deletions": 1, "labels": [], "user": {"login": "Codertocat"}`
+	sampleCommit     = `"sha": "a10867b14bb761a232cd80139fbd4c0d33264240", "context": "default", "commit": {"html_url": "https://github.com/Codertocat/Hello-World/commit/a10867b14bb761a232cd80139fbd4c0d33264240", "commit": {"message": "Initial commit"}}`
+)
+
+// samples are the payloads of the main events, by "event_action".
+var samples = map[string]sample{
+	"issue_opened": {"issues", `{"action": "opened", ` + sampleIssue + `, ` + sampleRepository + `, ` + sampleSender + `}`},
+	"issue_closed": {"issues", `{"action": "closed", ` + sampleIssue + `, ` + sampleRepository + `, ` + sampleSender + `}`},
+	"issue_comment": {"issue_comment", `{"action": "created", ` + sampleIssue + `, "comment": {"body": "You are totally right! I'll get this fixed right away.", "html_url": "https://github.com/Codertocat/Hello-World/issues/2#issuecomment-393304133"}, ` +
+		sampleRepository + `, ` + sampleSender + `}`},
+	"pull_request_opened": {"pull_request", `{"action": "opened", ` + samplePR + `, "merged": false}, ` + sampleRepository + `, ` + sampleSender + `}`},
+	"pull_request_merged": {"pull_request", `{"action": "closed", ` + samplePR + `, "merged": true}, ` + sampleRepository + `, ` + sampleSender + `}`},
+	"pull_request_review": {"pull_request_review", `{"action": "submitted", "review": {"state": "approved", "body": "Looks good to me", "html_url": "https://github.com/Codertocat/Hello-World/pull/1#pullrequestreview-1"}, ` +
+		samplePR + `}, ` + sampleRepository + `, ` + sampleSender + `}`},
+	"status_success": {"status", `{"state": "success", ` + sampleCommit + `, ` + sampleRepository + `, ` + sampleSender + `}`},
+	"status_failure": {"status", `{"state": "failure", ` + sampleCommit + `, ` + sampleRepository + `, ` + sampleSender + `}`},
+	"page_build":     {"page_build", `{"build": {"status": "built", "pusher": {"login": "Codertocat", "html_url": "https://github.com/Codertocat"}}, ` + sampleRepository + `, ` + sampleSender + `}`},
+	"ping":           {"ping", `{"zen": "Favor focus over features.", ` + sampleRepository + `, ` + sampleSender + `}`},
+}
+
+// SampleNames returns the names of the built-in samples, sorted.
+func SampleNames() []string {
+	var names []string
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sample parses the built-in sample with the given name, as in "issue_opened",
+// the way it would parse the event coming from GitHub. It's signed with the
+// secret of the options, if there's one.
+func Sample(name string, o Options) (Message, error) {
+	s, ok := samples[name]
+	if !ok {
+		return Message{}, fmt.Errorf("gh: unknown sample %q, expected one of %s", name, strings.Join(SampleNames(), ", "))
+	}
+
+	r, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(s.Payload)))
+	if err != nil {
+		return Message{}, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-GitHub-Event", s.Event)
+	if o.Secret != "" {
+		mac := hmac.New(sha1.New, []byte(o.Secret))
+		mac.Write([]byte(s.Payload))
+		r.Header.Set("X-Hub-Signature", "sha1="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return Parse(r, o)
+}
@@ -0,0 +1,115 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SecurityMarker starts every security message, so they stand out.
+const SecurityMarker = "🔒"
+
+// vulnerabilityAlertPayload holds the fields we use of the
+// repository_vulnerability_alert event.
+type vulnerabilityAlertPayload struct {
+	Action string `json:"action"`
+	Alert  struct {
+		AffectedPackageName string `json:"affected_package_name"`
+		AffectedRange       string `json:"affected_range"`
+		FixedIn             string `json:"fixed_in"`
+		ExternalReference   string `json:"external_reference"`
+		ExternalIdentifier  string `json:"external_identifier"`
+		Severity            string `json:"severity"`
+	} `json:"alert"`
+	Repository rawRepository `json:"repository"`
+	Sender     rawUser       `json:"sender"`
+}
+
+// formatVulnerabilityAlert reports the Dependabot alerts of a repository.
+func formatVulnerabilityAlert(payload []byte, o Options) (string, error) {
+	var p vulnerabilityAlertPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	alert := p.Alert
+
+	switch p.Action {
+	case "create":
+		message := fmt.Sprintf(
+			"%s Vulnerability alert%s in %s: `%s` %s",
+			SecurityMarker, severity(alert.Severity), p.Repository.link(),
+			alert.AffectedPackageName, alert.AffectedRange,
+		)
+		if alert.FixedIn != "" {
+			message += fmt.Sprintf(", fixed in %s", alert.FixedIn)
+		}
+		if alert.ExternalReference != "" {
+			message += " " + alert.ExternalReference
+		}
+		return message, nil
+	case "dismiss":
+		return fmt.Sprintf(
+			"%s %s dismissed the vulnerability alert for `%s` in %s",
+			SecurityMarker, p.Sender.sender(o).Link(), alert.AffectedPackageName, p.Repository.link(),
+		), nil
+	case "resolve":
+		return fmt.Sprintf(
+			"%s The vulnerability alert for `%s` in %s was resolved",
+			SecurityMarker, alert.AffectedPackageName, p.Repository.link(),
+		), nil
+	}
+
+	return "", o.allow(filtered("gh: not allowed vulnerability alert action, %s", p.Action))
+}
+
+// securityAdvisoryPayload holds the fields we use of the security_advisory
+// event. It has no repository, since advisories are global.
+type securityAdvisoryPayload struct {
+	Action   string `json:"action"`
+	Advisory struct {
+		GHSAID          string `json:"ghsa_id"`
+		Summary         string `json:"summary"`
+		Severity        string `json:"severity"`
+		Vulnerabilities []struct {
+			Package struct {
+				Ecosystem string `json:"ecosystem"`
+				Name      string `json:"name"`
+			} `json:"package"`
+		} `json:"vulnerabilities"`
+	} `json:"security_advisory"`
+}
+
+// formatSecurityAdvisory reports the advisories published on GitHub.
+func formatSecurityAdvisory(payload []byte, o Options) (string, error) {
+	var p securityAdvisoryPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	if p.Action != "published" && p.Action != "updated" {
+		return "", o.allow(filtered("gh: not allowed security advisory action, %s", p.Action))
+	}
+	advisory := p.Advisory
+
+	var packages []string
+	for _, v := range advisory.Vulnerabilities {
+		packages = append(packages, fmt.Sprintf("`%s/%s`", v.Package.Ecosystem, v.Package.Name))
+	}
+	var affecting string
+	if len(packages) > 0 {
+		affecting = " affecting " + strings.Join(packages, ", ")
+	}
+
+	return fmt.Sprintf(
+		"%s Security advisory %s%s: %s%s https://github.com/advisories/%s",
+		SecurityMarker, p.Action, severity(advisory.Severity),
+		EscapeMarkdown(advisory.Summary), affecting, advisory.GHSAID,
+	), nil
+}
+
+// severity returns the severity between parentheses, if any.
+func severity(s string) string {
+	if s == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", s)
+}
@@ -6,9 +6,37 @@ import "fmt"
 type Sender struct {
 	Login   string
 	HTMLURL string
+	// Format sets how the sender is shown: "link" (the default) links the
+	// login to the GitHub profile, "plain" shows the login alone, and
+	// "mention" shows it as an @mention.
+	Format string
 }
 
-// Link returns a string with the URL for the Sender GitHub profile.
+// UnknownSender is shown instead of the senders without a login, which some
+// events triggered by GitHub itself or by deploy keys have.
+const UnknownSender = "someone"
+
+// Link returns a string with the URL for the Sender GitHub profile, or only
+// the login, as set by the Format. The login is shown alone if there's no
+// profile, and the senders without a login are the UnknownSender.
 func (s Sender) Link() string {
+	if s.Login == "" {
+		return UnknownSender
+	}
+	if s.HTMLURL == "" && s.Format != "mention" {
+		s.Format = "plain"
+	}
+	switch s.Format {
+	case "plain":
+		return EscapeMarkdown(s.Login)
+	case "mention":
+		return "@" + EscapeMarkdown(s.Login)
+	}
 	return fmt.Sprintf("[%s](%s)", s.Login, s.HTMLURL)
 }
+
+// sender returns the sender with the given login, shown as set by the
+// options.
+func (o Options) sender(login string, htmlURL string) Sender {
+	return Sender{Login: login, HTMLURL: htmlURL, Format: o.SenderFormat}
+}
@@ -0,0 +1,26 @@
+package gh
+
+// The severities of the messages, from the least to the most urgent.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// severityOf returns the severity set in Options.Severities for the event and
+// its action (or state), or for the event alone. By default, the failures are
+// critical and everything else is info.
+func (o Options) severityOf(event string, action string, failed bool) string {
+	if action != "" {
+		if severity, ok := o.Severities[event+":"+action]; ok {
+			return severity
+		}
+	}
+	if severity, ok := o.Severities[event]; ok {
+		return severity
+	}
+	if failed {
+		return SeverityCritical
+	}
+	return SeverityInfo
+}
@@ -0,0 +1,73 @@
+package gh
+
+import (
+	"regexp"
+	"time"
+)
+
+// Shortener shortens the links of the messages, such as through a link
+// shortening service.
+type Shortener interface {
+	Shorten(url string) (string, error)
+}
+
+// githubLink matches the links to GitHub in the messages, up to the end of a
+// Markdown link. The profiles, as in "https://github.com/Codertocat", are left
+// out, since they're short already.
+var githubLink = regexp.MustCompile(`https://github\.com/[^\s()\[\]/]+/[^\s()\[\]]+`)
+
+// shortenTimeout is how long the links of a message are waited to be
+// shortened, all of them together, since GitHub waits for the message to be
+// sent. It's replaced by the tests.
+var shortenTimeout = 2 * time.Second
+
+// shortenLinks replaces the links to GitHub in the text with the ones of the
+// Shortener, if there's one and the event is in the ShortenEvents (or there are
+// none). The links are shortened at once, and the ones that can't be shortened
+// within the shortenTimeout are left as they are.
+func (o Options) shortenLinks(event string, text string) string {
+	if o.Shortener == nil || (len(o.ShortenEvents) > 0 && !contains(o.ShortenEvents, event)) {
+		return text
+	}
+
+	type result struct {
+		link  string
+		short string
+	}
+	var links []string
+	for _, link := range githubLink.FindAllString(text, -1) {
+		if !contains(links, link) {
+			links = append(links, link)
+		}
+	}
+	// The results are buffered, so the late ones don't block.
+	results := make(chan result, len(links))
+	for _, link := range links {
+		go func(link string) {
+			short, err := o.Shortener.Shorten(link)
+			if err != nil || short == "" {
+				short = link
+			}
+			results <- result{link, short}
+		}(link)
+	}
+
+	shortened := map[string]string{}
+	timeout := time.After(shortenTimeout)
+wait:
+	for range links {
+		select {
+		case r := <-results:
+			shortened[r.link] = r.short
+		case <-timeout:
+			break wait
+		}
+	}
+
+	return githubLink.ReplaceAllStringFunc(text, func(link string) string {
+		if short, ok := shortened[link]; ok {
+			return short
+		}
+		return link
+	})
+}
@@ -0,0 +1,39 @@
+package gh
+
+import "fmt"
+
+// PRSizes are the size categories of the pull requests, from the smallest.
+var PRSizes = []string{"XS", "S", "M", "L", "XL"}
+
+// DefaultPRSizeThresholds are the lines changed (additions plus deletions)
+// from which a pull request is S, M, L and XL. Below the first one, it's XS.
+var DefaultPRSizeThresholds = []int{10, 100, 500, 1000}
+
+// prSizeThresholds returns the PRSizeThresholds, or the default ones unless
+// there's one for each size but XS.
+func (o Options) prSizeThresholds() []int {
+	if len(o.PRSizeThresholds) != len(PRSizes)-1 {
+		return DefaultPRSizeThresholds
+	}
+	return o.PRSizeThresholds
+}
+
+// prSize returns the size category of a pull request with the given changes.
+func (o Options) prSize(additions int64, deletions int64) string {
+	size := PRSizes[0]
+	for i, threshold := range o.prSizeThresholds() {
+		if additions+deletions >= int64(threshold) {
+			size = PRSizes[i+1]
+		}
+	}
+	return size
+}
+
+// diffStats returns the additions and deletions of a pull request, along with
+// its size if PRSize is set, as in "size: M (+120 −30)".
+func (o Options) diffStats(additions int64, deletions int64) string {
+	if o.PRSize {
+		return fmt.Sprintf("size: %s (+%d −%d)", o.prSize(additions, deletions), additions, deletions)
+	}
+	return fmt.Sprintf("Additions: %d Deletions: %d", additions, deletions)
+}
@@ -1,28 +1,156 @@
 package gh
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/go-playground/webhooks.v5/github"
+)
 
 type Status struct {
 	State   string
 	Message string
 	HTMLURL string
+	// SHA is the commit the status is about, and Context the check that
+	// reported it, as in "ci/lint".
+	SHA     string
+	Context string
+	// Repository is the full name of the repository of the commit, as in
+	// "owner/repo".
+	Repository string
+	// Committer is shown along with who the status is by, when it's set and
+	// it's someone else, as in "authored by X, committed by Y".
+	Committer Sender
+}
+
+// NoCommitMessage stands in for the message of the commits that come without
+// one, as in partial payloads.
+const NoCommitMessage = "(no commit message)"
+
+// newStatus returns the status of the payload. The commit can be missing from
+// partial payloads, in which case it's linked through the repository.
+func newStatus(p github.StatusPayload) Status {
+	status := Status{
+		State:      p.State,
+		Message:    subject(p.Commit.Commit.Message),
+		HTMLURL:    p.Commit.HTMLURL,
+		SHA:        p.Sha,
+		Context:    p.Context,
+		Repository: p.Repository.FullName,
+	}
+	if status.Message == "" {
+		status.Message = NoCommitMessage
+	}
+	if status.HTMLURL == "" && p.Repository.HTMLURL != "" && p.Sha != "" {
+		status.HTMLURL = p.Repository.HTMLURL + "/commit/" + p.Sha
+	}
+	return status
+}
+
+// subject returns the first line of a commit message. The rest of it can be
+// read following the link to the commit.
+func subject(message string) string {
+	return strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
 }
 
 // NotAllowed returns an error if the Status' State is not allowed to be
-// handled.
-func (s Status) NotAllowed() error {
-	if s.State == "pending" {
-		return fmt.Errorf("gh: not allowed status, pending")
+// handled. Only the StatusStates are, or every state but pending if there are
+// none.
+func (s Status) NotAllowed(o Options) error {
+	if len(o.StatusStates) > 0 && !contains(o.StatusStates, s.State) {
+		return filtered("gh: not allowed status, %s", s.State)
+	}
+	if len(o.StatusStates) == 0 && s.State == "pending" {
+		return filtered("gh: not allowed status, pending")
 	}
 
 	return nil
 }
 
+// Failed returns true if the status reports a failure or an error.
+func (s Status) Failed() bool {
+	return s.State == "failure" || s.State == "error"
+}
+
+// DefaultStatusEmoji is how each state is presented when no other
+// presentation is configured.
+var DefaultStatusEmoji = map[string]string{
+	"success": "✅",
+	"failure": "❌",
+	"error":   "🔥",
+}
+
 // Format returns a string with a formatted message to be sent for this status
-// with the passed sender.
-func (status Status) Format(s Sender) string {
+// with the passed sender. The state is replaced by its entry in the emoji map,
+// states without one are shown as they come. There's no duration of the run, as
+// GitHub sends each status as it's created, with the same created_at and
+// updated_at, and the payload doesn't say when the pending one was.
+func (status Status) Format(s Sender, emoji map[string]string) string {
+	state, ok := emoji[status.State]
+	if !ok {
+		state = fmt.Sprintf("`%s`:", status.State)
+	}
+
+	by := "by " + s.Link()
+	if status.Committer.Login != "" && status.Committer.Login != s.Login {
+		by = fmt.Sprintf("authored by %s, committed by %s", s.Link(), status.Committer.Link())
+	}
+
 	return fmt.Sprintf(
-		"`%s`: [%s](%s) by %s",
-		status.State, status.Message, status.HTMLURL, s.Link(),
+		"%s [%s](%s) %s",
+		state, status.Message, status.HTMLURL, by,
+	)
+}
+
+// statusPeople returns who the status is by, as set by StatusPeople: the author
+// of the commit, its committer, or both. The sender stands in for them when
+// the payload doesn't have the commit.
+func (o Options) statusPeople(p github.StatusPayload, status *Status) Sender {
+	author := o.sender(p.Commit.Author.Login, p.Commit.Author.HTMLURL)
+	committer := o.sender(p.Commit.Committer.Login, p.Commit.Committer.HTMLURL)
+	if author.Login == "" {
+		author = o.sender(p.Sender.Login, p.Sender.HTMLURL)
+	}
+
+	switch o.StatusPeople {
+	case "committer":
+		if committer.Login != "" {
+			return committer
+		}
+	case "both":
+		status.Committer = committer
+	}
+	return author
+}
+
+// FormatStatuses returns a single message summing up the statuses of a commit,
+// as in "3/3 checks passed" or "2 passed, 1 failed".
+func FormatStatuses(statuses []Status, o Options) string {
+	if len(statuses) == 0 {
+		return ""
+	}
+
+	var passed, failed int
+	for _, status := range statuses {
+		if status.Failed() {
+			failed++
+		} else {
+			passed++
+		}
+	}
+
+	emoji := o.statusEmoji()
+	last := statuses[len(statuses)-1]
+	if failed == 0 {
+		return fmt.Sprintf(
+			"%s %d/%d checks passed on [%s](%s)",
+			emoji["success"], passed, len(statuses), last.Message, last.HTMLURL,
+		)
+	}
+
+	message := fmt.Sprintf(
+		"%s %d passed, %d failed on [%s](%s)",
+		emoji["failure"], passed, failed, last.Message, last.HTMLURL,
 	)
+	return o.withMentions("status", message)
 }
@@ -0,0 +1,26 @@
+package gh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shortSHA is how many characters of the commits are shown, as GitHub does.
+const shortSHA = 7
+
+// synchronize returns the text of the synchronize action of the pull requests,
+// linking to their new head commit, as in "X pushed 34c5c77 to PR #1: Title
+// url". It returns false for the other actions, or if the head is unknown.
+func synchronize(c Content, s Sender, number int64, sha string) (string, bool) {
+	if c.Action != "synchronize" || sha == "" {
+		return "", false
+	}
+
+	short := sha
+	if len(short) > shortSHA {
+		short = short[:shortSHA]
+	}
+	commit := fmt.Sprintf("[%s](%s/commits/%s)", short, strings.TrimSuffix(c.HTMLURL, "/"), sha)
+
+	return fmt.Sprintf("%s pushed %s to PR #%d: %s %s%s", s.Link(), commit, number, c.Title, c.HTMLURL, c.details()), true
+}
@@ -0,0 +1,48 @@
+package gh
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// templateFor returns the template set for the event in the repository, or in
+// every repository, if any. The templates of an "event:action" are picked over
+// the ones of the whole event.
+func (o Options) templateFor(repo string, event string, action string) string {
+	kinds := []string{event + ":" + action, event}
+	for _, templates := range []map[string]string{o.RepoTemplates[repo], o.Templates} {
+		for _, kind := range kinds {
+			if template, ok := templates[kind]; ok {
+				return template
+			}
+		}
+	}
+	return ""
+}
+
+// fill replaces the {event}, {action}, {sender}, {repo} and {message}
+// placeholders of the template with the ones of the event. The message is the
+// one telebot would send without the template. The payloads that can't be
+// read return an error, instead of a message without the placeholders.
+func fill(template string, event string, payload []byte, message string, o Options) (string, error) {
+	var p struct {
+		Sender     rawUser       `json:"sender"`
+		Repository rawRepository `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+
+	var repo string
+	if p.Repository.FullName != "" {
+		repo = p.Repository.link()
+	}
+
+	return strings.NewReplacer(
+		"{event}", EscapeMarkdown(event),
+		"{action}", EscapeMarkdown(actionOf(payload)),
+		"{sender}", p.Sender.sender(o).Link(),
+		"{repo}", repo,
+		"{message}", message,
+	).Replace(template), nil
+}
@@ -0,0 +1,49 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// issueTransferPayload holds the fields we use of the issues transferred to
+// another repository. The webhooks library drops their changes.
+type issueTransferPayload struct {
+	Action  string `json:"action"`
+	Changes struct {
+		NewIssue      *rawIssue      `json:"new_issue"`
+		NewRepository *rawRepository `json:"new_repository"`
+	} `json:"changes"`
+	Issue struct {
+		Number  int64  `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+	} `json:"issue"`
+	Sender rawUser `json:"sender"`
+}
+
+// formatIssueTransfer reports the issues moved to another repository. The new
+// repository is left out of the payload if the Webhook can't see it, as when
+// it's private.
+func formatIssueTransfer(payload []byte, o Options) (string, error) {
+	var p issueTransferPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	if err := o.allow(Content{Action: p.Action}.NotAllowed(o)); err != nil {
+		return "", err
+	}
+
+	repo := "another repository"
+	if p.Changes.NewRepository != nil && p.Changes.NewRepository.FullName != "" {
+		repo = p.Changes.NewRepository.link()
+	}
+	htmlURL := p.Issue.HTMLURL
+	if p.Changes.NewIssue != nil && p.Changes.NewIssue.HTMLURL != "" {
+		htmlURL = p.Changes.NewIssue.HTMLURL
+	}
+
+	return fmt.Sprintf(
+		"%s transferred issue #%d to %s: %s %s",
+		p.Sender.sender(o).Link(), p.Issue.Number, repo, p.Issue.Title, htmlURL,
+	), nil
+}
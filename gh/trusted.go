@@ -0,0 +1,31 @@
+package gh
+
+import (
+	"net"
+	"net/http"
+)
+
+// skipsSignature returns true if the signature of the request doesn't have to
+// be checked, which only happens with AcceptUnsigned set and a request coming
+// from one of the TrustedNetworks.
+func (o Options) skipsSignature(r *http.Request) bool {
+	if !o.AcceptUnsigned {
+		return false
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range o.TrustedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// workflowJobPayload holds the fields we use of the workflow_job event, which
+// the webhooks library doesn't know.
+type workflowJobPayload struct {
+	Action      string `json:"action"`
+	WorkflowJob struct {
+		Name         string    `json:"name"`
+		WorkflowName string    `json:"workflow_name"`
+		Status       string    `json:"status"`
+		Conclusion   string    `json:"conclusion"`
+		HTMLURL      string    `json:"html_url"`
+		RunnerName   string    `json:"runner_name"`
+		Labels       []string  `json:"labels"`
+		StartedAt    time.Time `json:"started_at"`
+		CompletedAt  time.Time `json:"completed_at"`
+	} `json:"workflow_job"`
+}
+
+// failed returns true if the job finished without succeeding.
+func (p workflowJobPayload) failed() bool {
+	return failedConclusion(p.WorkflowJob.Conclusion)
+}
+
+// failedConclusion returns true if the conclusion of a job or a check run is
+// not a success. The cancelled, the skipped and the neutral ones didn't fail.
+func failedConclusion(conclusion string) bool {
+	switch conclusion {
+	case "failure", "timed_out", "action_required", "startup_failure":
+		return true
+	}
+	return false
+}
+
+// workflowJobFailed returns true if the payload is of a failed job.
+func workflowJobFailed(payload []byte) bool {
+	var p workflowJobPayload
+	return json.Unmarshal(payload, &p) == nil && p.failed()
+}
+
+// formatWorkflowJob reports the jobs of the workflows, and the runners they're
+// run on, as in "Job `build` completed with `success` on `runner-1`". Only the
+// completed ones are sent by default, the queued and the started ones are
+// among the DefaultIgnoredActions.
+func formatWorkflowJob(payload []byte, o Options) (string, error) {
+	var p workflowJobPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	if err := o.allow(Content{Action: p.Action}.NotAllowed(o)); err != nil {
+		return "", err
+	}
+
+	job := p.WorkflowJob
+	message := fmt.Sprintf("Job `%s`", job.Name)
+	if job.WorkflowName != "" {
+		message += fmt.Sprintf(" of `%s`", job.WorkflowName)
+	}
+	switch p.Action {
+	case "completed":
+		emoji := o.statusEmoji()["success"]
+		if p.failed() {
+			emoji = o.statusEmoji()["failure"]
+		}
+		message = fmt.Sprintf("%s %s completed with `%s`", emoji, message, job.Conclusion)
+	case "in_progress":
+		message += " started"
+	default:
+		message += " " + p.Action
+	}
+	if job.RunnerName != "" {
+		message += fmt.Sprintf(" on `%s`", job.RunnerName)
+	}
+	if len(job.Labels) > 0 {
+		message += fmt.Sprintf(" (labels: `%s`)", strings.Join(job.Labels, "`, `"))
+	}
+	if d := runDuration(job.StartedAt, job.CompletedAt); p.Action == "completed" && d > 0 {
+		message += " in " + formatDuration(d)
+	}
+
+	return message + ": " + job.HTMLURL, nil
+}
@@ -1,49 +1,250 @@
-package main
+// Package telebot holds the HTTP handlers that receive GitHub's Webhooks and
+// forward them to Telegram.
+package telebot
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
-	"os"
+	"strings"
+	"sync"
 
 	"github.com/berserktech/telebot/gh"
-	"github.com/berserktech/telebot/tg"
 )
 
 // Handler
 // =======
 
+// handlerBot is the bot of the Handler, made on its first call. It's kept for
+// as long as the instance of Zeit is, so that its Telegram client is made only
+// once, unless it fails to be.
+var handlerBot struct {
+	sync.Mutex
+	bot *Bot
+}
+
+// Handler is the function used by Zeit. It sends every message to the chat
+// set in the TELEGRAM_CHAT_ID environment variable. Nothing is served while
+// the configuration can't be read, with a 500.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	handlerBot.Lock()
+	if handlerBot.bot == nil {
+		config, err := ConfigFromEnv()
+		if err != nil {
+			handlerBot.Unlock()
+			log.Print(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		handlerBot.bot = NewBot(config)
+	}
+	bot := handlerBot.bot
+	handlerBot.Unlock()
+
+	// How to get the TELEGRAM_CHAT_ID: https://stackoverflow.com/questions/32423837/telegram-bot-how-to-get-a-group-chat-id
+	println("Chat ID:", bot.config.ChatID)
+
+	Recover(bot.Handler(bot.config.ChatID))(w, r)
+}
+
+// Handler returns a handler that sends the messages built out of GitHub's
+// Webhooks to the given Telegram chat. It allows the standalone server to bind
+// a different chat to each one of its routes.
+//
 // IMPORTANT: the "println" calls in this function are mainly because I was
 // struggling trying to set up the environment variables on Zeit.co
 // Let's leave them where they are for now since we might continue playing around with the
 // hosting platform. We can improve them, for sure.
-func Handler(w http.ResponseWriter, r *http.Request) {
-	// Getting the message from GitHub
-	secret := os.Getenv("GITHUB_CLIENT_SECRET")
-	message, err := gh.GetMessage(r, secret)
-	if err != nil {
-		log.Print(err)
-		fmt.Fprintf(w, "%s", err)
-		return
+func (b *Bot) Handler(chatId string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := b.config
+
+		// Reading the body up front so that huge requests are rejected before
+		// we try to parse them.
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, config.MaxBodyBytes))
+		if err != nil {
+			b.logError(err)
+			if int64(len(body)) >= config.MaxBodyBytes {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			} else {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		// Getting the message from GitHub
+		message, err := gh.Parse(r, config.GitHub)
+		if err != nil {
+			b.writeParseError(w, err)
+			return
+		}
+		// The duplicates are told by the text of the event, not by the
+		// summary of the reviews it gets.
+		dedupText := message.Text
+		if b.approvals != nil {
+			message = b.approvals.track(message)
+		}
+		println("Message:")
+		println(message.Text)
+
+		// The event was dropped on purpose, there's nothing to send.
+		if blank(message.Text) {
+			fmt.Fprint(w, "Nothing to send")
+			return
+		}
+
+		if config.Token == "" {
+			println("No token received")
+		}
+
+		chatId := b.chatFor(message, chatId)
+		if chatId == "" {
+			fmt.Fprint(w, "Not routed")
+			return
+		}
+
+		if b.dedup != nil && b.dedup.duplicate(chatId, dedupText) {
+			fmt.Fprint(w, "Already sent")
+			return
+		}
+
+		if b.throttle != nil && !b.throttle.allow(chatId, message.Repository, message.Event) {
+			fmt.Fprint(w, "Throttled")
+			return
+		}
+
+		// The statuses of the same commit can be sent together, later on.
+		if b.statuses != nil && message.Status != nil {
+			b.statuses.add(chatId, *message.Status)
+			b.remember(chatId, dedupText)
+			fmt.Fprintf(w, "Coalescing:\n%s", message.Text)
+			return
+		}
+
+		b.ping(message, chatId)
+
+		// In server mode the message is sent in the background.
+		if b.queue != nil {
+			result := "Queued"
+			if b.enqueue(message, chatId, dedupText) {
+				result = "Batched"
+			}
+			b.writeSuccess(w, result, message, chatId)
+			return
+		}
+
+		// Sending the message to Telegram. The failures are a 502, so that
+		// GitHub shows them, and they can be redelivered.
+		if err := b.send(message, chatId); err != nil {
+			b.logError(err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		b.remember(chatId, dedupText)
+
+		b.writeSuccess(w, "Sent", message, chatId)
 	}
-	println("Message:")
-	println(message)
+}
 
-	token := os.Getenv("TELEGRAM_TOKEN")
-	if token == "" {
-		println("No token received")
+// writeParseError answers to GitHub with the status of the kind of the error.
+// The events dropped on purpose are still a 200, and they aren't logged as
+// errors.
+func (b *Bot) writeParseError(w http.ResponseWriter, err error) {
+	switch gh.KindOf(err) {
+	case gh.ErrFiltered:
+		println("Filtered:", err.Error())
+		fmt.Fprint(w, b.filteredBody(err))
+		return
+	case gh.ErrUnsupported:
+		b.logError(err)
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	case gh.ErrSignature:
+		b.logError(err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	default:
+		b.logError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 	}
+}
 
-	// How to get the TELEGRAM_CHAT_ID: https://stackoverflow.com/questions/32423837/telegram-bot-how-to-get-a-group-chat-id
-	chatId := os.Getenv("TELEGRAM_CHAT_ID")
-	println("Chat ID:", chatId)
+// DefaultFilteredBody is the body of the response to the events dropped on
+// purpose, unless Config.FilteredBody is set.
+const DefaultFilteredBody = "filtered: {reason}"
 
-	// Sending the message to Telegram
-	if err := tg.Send(message, token, chatId); err != nil {
-		log.Print(err)
-		fmt.Fprintf(w, "%s", err)
+// filteredBody returns the body of the response to an event dropped on
+// purpose, as set by Config.FilteredBody. It's empty if it's "none".
+func (b *Bot) filteredBody(err error) string {
+	body := b.config.FilteredBody
+	switch body {
+	case "":
+		body = DefaultFilteredBody
+	case "none":
+		return ""
+	}
+	return strings.Replace(body, "{reason}", strings.TrimPrefix(err.Error(), "gh: "), -1)
+}
+
+// writeSuccess answers to GitHub once the message is sent, or queued, as set by
+// Config.SuccessStatus and Config.SuccessBody. By default, it's a 200 with
+// "Sent:" (or "Queued:") and the message.
+func (b *Bot) writeSuccess(w http.ResponseWriter, result string, message gh.Message, chatId string) {
+	if b.config.SuccessStatus != 0 {
+		w.WriteHeader(b.config.SuccessStatus)
+	}
+	if b.config.SuccessBody == "" {
+		fmt.Fprintf(w, "%s:\n%s", result, message.Text)
 		return
 	}
+	fmt.Fprint(w, strings.NewReplacer(
+		"{result}", result,
+		"{message}", message.Text,
+		"{event}", message.Event,
+		"{chat}", chatId,
+	).Replace(b.config.SuccessBody))
+}
 
-	fmt.Fprintf(w, "Sent:\n%s", message)
+// ChatFromPath returns a handler that takes the Telegram chat ID out of the
+// last segment of the request's path, so that "/github/123" sends the messages
+// to the chat 123. The paths whose last segment is empty, as in "/github/", or
+// isn't a chat ID are not found.
+func (b *Bot) ChatFromPath() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		segment := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if segment == "" {
+			http.Error(w, "telebot: missing chat ID in path", http.StatusNotFound)
+			return
+		}
+		chatId, err := NormalizeChatID(segment)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		b.Handler(chatId)(w, r)
+	}
+}
+
+// Metrics returns a handler that reports the state of the bot in the
+// Prometheus text format.
+func (b *Bot) Metrics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := breakerClosed
+		if b.breaker != nil {
+			state = b.breaker.State()
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintln(w, "# HELP telebot_breaker_state The state of the breaker around the Telegram sends.")
+		fmt.Fprintln(w, "# TYPE telebot_breaker_state gauge")
+		for _, s := range []string{breakerClosed, breakerOpen, breakerHalfOpen} {
+			value := 0
+			if s == state {
+				value = 1
+			}
+			fmt.Fprintf(w, "telebot_breaker_state{state=%q} %d\n", s, value)
+		}
+	}
 }
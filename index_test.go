@@ -0,0 +1,236 @@
+package telebot
+
+import (
+	"errors"
+	"github.com/berserktech/telebot/gh"
+	"github.com/berserktech/telebot/tg"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerBodyTooLarge(t *testing.T) {
+	os.Setenv("MAX_BODY_BYTES", "10")
+	defer os.Unsetenv("MAX_BODY_BYTES")
+
+	request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+	request.Header.Add("X-GitHub-Event", "ping")
+	recorder := httptest.NewRecorder()
+	config, err := ConfigFromEnv()
+	assert.Nil(t, err)
+	NewBot(config).Handler("123")(recorder, request)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+}
+
+func TestHandlerParseErrors(t *testing.T) {
+	cases := []struct {
+		event, secret, signature, body string
+		code                           int
+	}{
+		{"reaction", "", "", `{"action": "created"}`, http.StatusOK},
+		{"org_block", "", "", `{"action": "blocked"}`, http.StatusNotImplemented},
+		{"ping", "secret", "", `{"zen": "Favor focus over features."}`, http.StatusUnauthorized},
+		{"ping", "secret", "sha1=0000", `{"zen": "Favor focus over features."}`, http.StatusUnauthorized},
+		{"package", "", "", `{`, http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		bot, client := mockBot(Config{MaxBodyBytes: DefaultMaxBodyBytes, GitHub: gh.Options{Secret: c.secret}})
+		request := httptest.NewRequest("POST", "/", strings.NewReader(c.body))
+		request.Header.Add("X-GitHub-Event", c.event)
+		if c.signature != "" {
+			request.Header.Add("X-Hub-Signature", c.signature)
+		}
+		recorder := httptest.NewRecorder()
+		bot.Handler("123")(recorder, request)
+
+		assert.Equal(t, c.code, recorder.Code, c.event)
+		assert.Len(t, client.sent, 0)
+	}
+}
+
+func TestHandlerFiltered(t *testing.T) {
+	cases := []struct {
+		filteredBody, expected string
+	}{
+		{"", "filtered: not allowed action, labeled"},
+		{"ignored ({reason})", "ignored (not allowed action, labeled)"},
+		{"none", ""},
+	}
+
+	for _, c := range cases {
+		bot, client := mockBot(Config{MaxBodyBytes: DefaultMaxBodyBytes, FilteredBody: c.filteredBody})
+		request := httptest.NewRequest("POST", "/", strings.NewReader(`{"action": "labeled"}`))
+		request.Header.Add("X-GitHub-Event", "issues")
+		recorder := httptest.NewRecorder()
+		bot.Handler("123")(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, c.expected, recorder.Body.String())
+		assert.Len(t, client.sent, 0)
+	}
+}
+
+func TestMetricsBreaker(t *testing.T) {
+	bot := NewServerBot(Config{BreakerFailures: 1, BreakerCooldown: time.Minute})
+	bot.breaker.allow()
+	bot.breaker.done(errors.New("telegram is down"))
+
+	recorder := httptest.NewRecorder()
+	bot.Metrics()(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Contains(t, recorder.Body.String(), `telebot_breaker_state{state="open"} 1`)
+	assert.Contains(t, recorder.Body.String(), `telebot_breaker_state{state="closed"} 0`)
+}
+
+func TestHandlerSuccessResponse(t *testing.T) {
+	bot, _ := mockBot(Config{MaxBodyBytes: DefaultMaxBodyBytes, SuccessStatus: http.StatusAccepted, SuccessBody: "{result} {event} to {chat}"})
+
+	request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+	request.Header.Add("X-GitHub-Event", "ping")
+	recorder := httptest.NewRecorder()
+	bot.Handler("123")(recorder, request)
+
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, "Sent ping to 123", recorder.Body.String())
+}
+
+func TestHandlerDefaultSuccessResponse(t *testing.T) {
+	bot, _ := mockBot(Config{MaxBodyBytes: DefaultMaxBodyBytes})
+
+	request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+	request.Header.Add("X-GitHub-Event", "ping")
+	recorder := httptest.NewRecorder()
+	bot.Handler("123")(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "Sent:\nping", recorder.Body.String())
+}
+
+func TestHandlerRouteFallback(t *testing.T) {
+	cases := []struct {
+		fallback, expected string
+		sent               int
+	}{
+		{RouteFallbackDefault, "Sent:", 1},
+		{RouteFallbackDrop, "Not routed", 0},
+	}
+
+	for _, c := range cases {
+		bot, client := mockBot(Config{
+			MaxBodyBytes:  DefaultMaxBodyBytes,
+			RouteRules:    []RouteRule{{Pattern: "^acme/", ChatID: "111"}},
+			RouteFallback: c.fallback,
+		})
+		request := httptest.NewRequest("POST", "/", strings.NewReader(`{"action": "opened", "issue": {"number": 1, "title": "Spelling error in the README file", "html_url": "https://github.com/Codertocat/Hello-World/issues/1"}, "repository": {"full_name": "Codertocat/Hello-World"}, "sender": {"login": "Codertocat"}}`))
+		request.Header.Add("X-GitHub-Event", "issues")
+		recorder := httptest.NewRecorder()
+		bot.Handler("123")(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code, c.fallback)
+		assert.True(t, strings.HasPrefix(recorder.Body.String(), c.expected), recorder.Body.String())
+		assert.Len(t, client.sent, c.sent)
+	}
+}
+
+func TestHandlerClientRetried(t *testing.T) {
+	bot := NewBot(Config{MaxBodyBytes: DefaultMaxBodyBytes})
+	client := &mockClient{}
+	failures := 1
+	bot.newClient = func() (tg.TelegramClient, error) {
+		if failures > 0 {
+			failures--
+			return nil, errors.New("bad token")
+		}
+		return client, nil
+	}
+
+	for _, expected := range []struct {
+		code int
+		body string
+	}{
+		{http.StatusBadGateway, "bad token"},
+		{http.StatusOK, "Sent:"},
+	} {
+		request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+		request.Header.Add("X-GitHub-Event", "ping")
+		recorder := httptest.NewRecorder()
+		bot.Handler("123")(recorder, request)
+
+		assert.Equal(t, expected.code, recorder.Code)
+		assert.True(t, strings.HasPrefix(recorder.Body.String(), expected.body), recorder.Body.String())
+	}
+	assert.Len(t, client.sent, 1)
+}
+
+func TestHandlerKeepsBot(t *testing.T) {
+	bot := NewBot(Config{ChatID: "123", MaxBodyBytes: DefaultMaxBodyBytes})
+	client := &mockClient{}
+	made := 0
+	bot.newClient = func() (tg.TelegramClient, error) {
+		made++
+		return client, nil
+	}
+	handlerBot.bot = bot
+	defer func() { handlerBot.bot = nil }()
+
+	for i := 0; i < 2; i++ {
+		request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+		request.Header.Add("X-GitHub-Event", "ping")
+		recorder := httptest.NewRecorder()
+		Handler(recorder, request)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+	assert.Equal(t, 1, made)
+	assert.Len(t, client.sent, 2)
+}
+
+func TestHandlerMissingConfigFile(t *testing.T) {
+	os.Setenv("CONFIG_FILE", "fixtures/missing.json")
+	defer os.Unsetenv("CONFIG_FILE")
+
+	request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+	request.Header.Add("X-GitHub-Event", "ping")
+	recorder := httptest.NewRecorder()
+	Handler(recorder, request)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Nil(t, handlerBot.bot)
+}
+
+func TestChatFromPath(t *testing.T) {
+	cases := []struct {
+		path string
+		code int
+		chat int64
+	}{
+		{"/github/123", http.StatusOK, -123},
+		{"/github/-456", http.StatusOK, -456},
+		{"/github/", http.StatusNotFound, 0},
+		{"/github", http.StatusNotFound, 0},
+		{"/github/..", http.StatusNotFound, 0},
+		{"/", http.StatusNotFound, 0},
+	}
+
+	for _, c := range cases {
+		bot, client := mockBot(Config{MaxBodyBytes: DefaultMaxBodyBytes})
+		request := httptest.NewRequest("POST", c.path, strings.NewReader(`{"zen": "Favor focus over features."}`))
+		request.Header.Add("X-GitHub-Event", "ping")
+		recorder := httptest.NewRecorder()
+		bot.ChatFromPath()(recorder, request)
+
+		assert.Equal(t, c.code, recorder.Code, c.path)
+		if c.chat == 0 {
+			assert.Len(t, client.sent, 0, c.path)
+			continue
+		}
+		assert.Len(t, client.sent, 1, c.path)
+		assert.Equal(t, c.chat, client.sent[0].(tgbotapi.MessageConfig).ChatID, c.path)
+	}
+}
@@ -0,0 +1,69 @@
+package telebot
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// logLimiter collapses the identical log lines, such as the errors of a
+// misconfigured webhook that GitHub retries over and over. The first one is
+// logged right away, and the ones repeated within the window are counted, so
+// that a single summary of them is logged once the window is over.
+type logLimiter struct {
+	window time.Duration
+	// print logs a line. It's replaced by the tests.
+	print func(line string)
+
+	mu      sync.Mutex
+	repeats map[string]int
+}
+
+func newLogLimiter(window time.Duration) *logLimiter {
+	return &logLimiter{
+		window:  window,
+		print:   func(line string) { log.Print(line) },
+		repeats: map[string]int{},
+	}
+}
+
+// Print logs the line, unless it was already logged within the window. The
+// window starts with the first one.
+func (l *logLimiter) Print(v ...interface{}) {
+	line := fmt.Sprint(v...)
+
+	l.mu.Lock()
+	if _, ok := l.repeats[line]; ok {
+		l.repeats[line]++
+		l.mu.Unlock()
+		return
+	}
+	l.repeats[line] = 0
+	l.mu.Unlock()
+
+	time.AfterFunc(l.window, func() { l.reset(line) })
+	l.print(line)
+}
+
+// reset ends the window of the line, logging how many times it was repeated
+// within it, if any.
+func (l *logLimiter) reset(line string) {
+	l.mu.Lock()
+	repeats := l.repeats[line]
+	delete(l.repeats, line)
+	l.mu.Unlock()
+
+	if repeats > 0 {
+		l.print(fmt.Sprintf("%s (repeated %d times)", line, repeats))
+	}
+}
+
+// logError logs the error, through the log limiter if there's one.
+func (b *Bot) logError(err error) {
+	if b.logs == nil {
+		log.Print(err)
+		return
+	}
+	b.logs.Print(err)
+}
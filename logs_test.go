@@ -0,0 +1,32 @@
+package telebot
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLogLimiter(t *testing.T) {
+	lines := make(chan string, 10)
+	l := newLogLimiter(20 * time.Millisecond)
+	l.print = func(line string) { lines <- line }
+
+	for i := 0; i < 5; i++ {
+		l.Print(errors.New("HMAC verification failed"))
+	}
+	l.Print("another error")
+
+	assert.Equal(t, "HMAC verification failed", <-lines)
+	assert.Equal(t, "another error", <-lines)
+	assert.Equal(t, "HMAC verification failed (repeated 4 times)", <-lines)
+	select {
+	case line := <-lines:
+		t.Fatalf("unexpected line %q", line)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Once the window is over, the line is logged again.
+	l.Print(errors.New("HMAC verification failed"))
+	assert.Equal(t, "HMAC verification failed", <-lines)
+}
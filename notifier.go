@@ -0,0 +1,117 @@
+package telebot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/berserktech/telebot/gh"
+)
+
+// Notifier sends the messages somewhere else than Telegram.
+type Notifier interface {
+	Notify(message gh.Message, chatId string) error
+}
+
+// ContextNotifier is a Notifier that gives up once the context is done. The bot
+// calls NotifyContext instead of Notify when the notifier has it, so that it's
+// given up on after the Config.NotifierTimeout.
+type ContextNotifier interface {
+	Notifier
+	NotifyContext(ctx context.Context, message gh.Message, chatId string) error
+}
+
+// DefaultNotifierTimeout is how long a notifier, or Telegram, is waited for by
+// default.
+const DefaultNotifierTimeout = 10 * time.Second
+
+// WebhookNotifier POSTs the messages as JSON to an HTTP endpoint, for it to do
+// whatever it wants with them.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier returns a notifier that POSTs the messages to the URL.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: DefaultNotifierTimeout}}
+}
+
+// relayedMessage is what the WebhookNotifier POSTs.
+type relayedMessage struct {
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+	Repo     string `json:"repo"`
+	Sender   string `json:"sender"`
+	URL      string `json:"url"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// Notify POSTs the message. Any answer but a 2xx is an error.
+func (n *WebhookNotifier) Notify(message gh.Message, chatId string) error {
+	return n.NotifyContext(context.Background(), message, chatId)
+}
+
+// NotifyContext POSTs the message until the context is done.
+func (n *WebhookNotifier) NotifyContext(ctx context.Context, message gh.Message, chatId string) error {
+	body, err := json.Marshal(relayedMessage{
+		Kind:     message.Event,
+		Message:  message.Text,
+		Repo:     message.Repository,
+		Sender:   message.Sender,
+		URL:      message.URL,
+		Severity: message.Severity,
+	})
+	if err != nil {
+		return err
+	}
+
+	return postJSON(ctx, n.Client, "relay", n.URL, body)
+}
+
+// postJSON POSTs the JSON body to the URL through the client, until the context
+// is done. Any answer but a 2xx is an error, which names the notifier.
+func postJSON(ctx context.Context, client *http.Client, name string, url string, body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("telebot: %s failed, %s", name, err)
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("telebot: %s answered %s", name, response.Status)
+	}
+	return nil
+}
+
+// notifyErrors sums up the errors of sending a message to more than one place.
+type notifyErrors []error
+
+func (errs notifyErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// err returns nil if there are no errors, and the error itself if there's a
+// single one.
+func (errs notifyErrors) err() error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errs
+}
@@ -0,0 +1,105 @@
+package telebot
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/berserktech/telebot/gh"
+	"github.com/berserktech/telebot/tg"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	relayed := make(chan relayedMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m relayedMessage
+		json.NewDecoder(r.Body).Decode(&m)
+		relayed <- m
+	}))
+	defer server.Close()
+
+	bot, client := mockBot(Config{RelayURL: server.URL})
+	message := gh.Message{
+		Text:       "hello",
+		Event:      "issues",
+		Repository: "Codertocat/Hello-World",
+		Sender:     "Codertocat",
+		URL:        "https://github.com/Codertocat/Hello-World/issues/2",
+	}
+	assert.Nil(t, bot.send(message, "123"))
+
+	assert.Equal(t, relayedMessage{
+		Kind:    "issues",
+		Message: "hello",
+		Repo:    "Codertocat/Hello-World",
+		Sender:  "Codertocat",
+		URL:     "https://github.com/Codertocat/Hello-World/issues/2",
+	}, <-relayed)
+	assert.Len(t, client.sent, 1)
+}
+
+func TestWebhookNotifierRelayOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	bot, client := mockBot(Config{RelayURL: server.URL, RelayOnly: true})
+	assert.Nil(t, bot.send(gh.Message{Text: "hello"}, "123"))
+	assert.Len(t, client.sent, 0)
+}
+
+func TestWebhookNotifierTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	bot, _ := mockBot(Config{RelayURL: server.URL, NotifierTimeout: 20 * time.Millisecond})
+	start := time.Now()
+	err := bot.send(gh.Message{Text: "hello"}, "123")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "telebot: relay failed")
+	assert.Contains(t, err.Error(), "deadline exceeded")
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+// plainNotifier is a Notifier without a context, which records the messages.
+type plainNotifier struct {
+	sent []string
+}
+
+func (n *plainNotifier) Notify(message gh.Message, chatId string) error {
+	n.sent = append(n.sent, message.Text)
+	return nil
+}
+
+func TestPlainNotifier(t *testing.T) {
+	bot, client := mockBot(Config{})
+	notifier := &plainNotifier{}
+	bot.notifiers = append(bot.notifiers, notifier)
+
+	assert.Nil(t, bot.send(gh.Message{Text: "hello"}, "123"))
+	assert.Equal(t, []string{"hello"}, notifier.sent)
+	assert.Len(t, client.sent, 1)
+}
+
+func TestNotifyErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	bot := NewBot(Config{RelayURL: server.URL})
+	bot.newClient = func() (tg.TelegramClient, error) { return nil, errors.New("bad token") }
+
+	err := bot.send(gh.Message{Text: "hello"}, "123")
+	assert.EqualError(t, err, "bad token; telebot: relay answered 502 Bad Gateway")
+}
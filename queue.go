@@ -0,0 +1,53 @@
+package telebot
+
+import "sync"
+
+// queue runs jobs in the background. Jobs pushed with the same key (the chat
+// ID) run one at a time, in the order they were pushed, while jobs with
+// different keys run in parallel, up to the number of workers at a time.
+type queue struct {
+	mu      sync.Mutex
+	pending sync.WaitGroup
+	jobs    map[string]chan func()
+	// workers holds a token for each job running.
+	workers chan struct{}
+}
+
+// newQueue returns a queue that runs up to the given number of jobs at a time,
+// or a single one if it's not positive.
+func newQueue(workers int) *queue {
+	if workers < 1 {
+		workers = 1
+	}
+	return &queue{jobs: map[string]chan func(){}, workers: make(chan struct{}, workers)}
+}
+
+// push adds the job at the end of the key's queue. Each key gets its own
+// goroutine the first time it's used.
+func (q *queue) push(key string, job func()) {
+	q.mu.Lock()
+	jobs, ok := q.jobs[key]
+	if !ok {
+		jobs = make(chan func(), 100)
+		q.jobs[key] = jobs
+		go q.run(jobs)
+	}
+	q.pending.Add(1)
+	q.mu.Unlock()
+
+	jobs <- job
+}
+
+func (q *queue) run(jobs chan func()) {
+	for job := range jobs {
+		q.workers <- struct{}{}
+		job()
+		<-q.workers
+		q.pending.Done()
+	}
+}
+
+// wait blocks until every job pushed so far has run.
+func (q *queue) wait() {
+	q.pending.Wait()
+}
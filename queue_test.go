@@ -0,0 +1,64 @@
+package telebot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueueKeepsOrderPerKey(t *testing.T) {
+	q := newQueue(4)
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+
+	// Pushing from a single goroutine per key defines the receive order, while
+	// both keys are pushed concurrently.
+	var pushers sync.WaitGroup
+	for _, key := range []string{"a", "b"} {
+		pushers.Add(1)
+		go func(key string) {
+			defer pushers.Done()
+			for i := 0; i < 50; i++ {
+				i := i
+				q.push(key, func() {
+					// Making the earlier jobs slower, so that a queue that
+					// isn't serialized would finish them out of order.
+					time.Sleep(time.Duration(50-i) * 10 * time.Microsecond)
+					mu.Lock()
+					got[key] = append(got[key], i)
+					mu.Unlock()
+				})
+			}
+		}(key)
+	}
+	pushers.Wait()
+	q.wait()
+
+	for _, key := range []string{"a", "b"} {
+		assert.Len(t, got[key], 50)
+		for i, n := range got[key] {
+			assert.Equal(t, i, n)
+		}
+	}
+}
+
+// drain pushes a backlog of slow jobs, spread over a few keys, and returns how
+// long the queue takes to run them.
+func drain(workers int) time.Duration {
+	q := newQueue(workers)
+	start := time.Now()
+	for i := 0; i < 8; i++ {
+		q.push(string(rune('a'+i%4)), func() { time.Sleep(20 * time.Millisecond) })
+	}
+	q.wait()
+	return time.Since(start)
+}
+
+func TestQueueWorkers(t *testing.T) {
+	one, four := drain(1), drain(4)
+
+	assert.True(t, one >= 160*time.Millisecond, "a single worker took %s", one)
+	assert.True(t, four < one, "four workers took %s, a single one %s", four, one)
+}
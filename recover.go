@@ -0,0 +1,34 @@
+package telebot
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover returns a handler that answers with a 500 when the given one panics,
+// as it could with a malformed payload, instead of crashing. The stack is
+// logged along with the ID of the GitHub delivery, so it can be redelivered
+// once it's fixed.
+func Recover(h http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			delivery := r.Header.Get("X-GitHub-Delivery")
+			log.Printf("telebot: panic handling the delivery %q: %v\n%s", delivery, p, debug.Stack())
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error":    "telebot: internal error",
+				"delivery": delivery,
+			})
+		}()
+
+		h.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,57 @@
+package telebot
+
+import (
+	"log"
+	"regexp"
+)
+
+// RouteRule sends the messages of the repositories whose full name, as in
+// "owner/repo", matches the Pattern to the chat.
+type RouteRule struct {
+	Pattern string
+	ChatID  string
+}
+
+// The ways the messages of the repositories that match no RouteRule are
+// handled, as set by Config.RouteFallback.
+const (
+	// RouteFallbackDefault sends them to the chat of the handler.
+	RouteFallbackDefault = "default"
+	// RouteFallbackDrop drops them.
+	RouteFallbackDrop = "drop"
+)
+
+// routeRule is a RouteRule ready to be matched.
+type routeRule struct {
+	pattern *regexp.Regexp
+	chatId  string
+}
+
+// compileRules returns the rules ready to be matched, in the same order. The
+// ones with an invalid pattern are skipped.
+func compileRules(rules []RouteRule) []routeRule {
+	var compiled []routeRule
+	for _, rule := range rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			log.Printf("telebot: invalid route rule %q, skipping it: %s", rule.Pattern, err)
+			continue
+		}
+		compiled = append(compiled, routeRule{pattern: pattern, chatId: rule.ChatID})
+	}
+	return compiled
+}
+
+// routeFor returns the chat of the first rule matching the repository, or
+// false if there's none.
+func routeFor(rules []routeRule, repo string) (string, bool) {
+	if repo == "" {
+		return "", false
+	}
+	for _, rule := range rules {
+		if rule.pattern.MatchString(repo) {
+			return rule.chatId, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,47 @@
+package telebot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultShortenerTimeout is how long the LinkShortener waits for its endpoint.
+// It's short, since GitHub waits for the messages to be sent.
+const DefaultShortenerTimeout = 2 * time.Second
+
+// LinkShortener shortens the links through a service that answers with the
+// short link alone, such as "https://tinyurl.com/api-create.php?url={url}".
+type LinkShortener struct {
+	// Endpoint is the URL that's requested, with the link to shorten in
+	// place of its {url}.
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewLinkShortener returns a shortener that requests the endpoint.
+func NewLinkShortener(endpoint string) *LinkShortener {
+	return &LinkShortener{Endpoint: endpoint, Client: &http.Client{Timeout: DefaultShortenerTimeout}}
+}
+
+// Shorten returns the short link the endpoint answers. Any answer but a 2xx is
+// an error.
+func (s *LinkShortener) Shorten(link string) (string, error) {
+	response, err := s.Client.Get(strings.Replace(s.Endpoint, "{url}", url.QueryEscape(link), -1))
+	if err != nil {
+		return "", fmt.Errorf("telebot: shortener failed, %s", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", fmt.Errorf("telebot: shortener answered %s", response.Status)
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("telebot: shortener failed, %s", err)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
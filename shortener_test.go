@@ -0,0 +1,34 @@
+package telebot
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLinkShortener(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "https://github.com/Codertocat/Hello-World/issues/2", r.URL.Query().Get("url"))
+		fmt.Fprintln(w, "https://tinyurl.com/abc")
+	}))
+	defer server.Close()
+
+	short, err := NewLinkShortener(server.URL + "/?url={url}").Shorten("https://github.com/Codertocat/Hello-World/issues/2")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://tinyurl.com/abc", short)
+}
+
+func TestLinkShortenerFailing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := NewLinkShortener(server.URL + "/?url={url}").Shorten("https://github.com")
+	assert.EqualError(t, err, "telebot: shortener answered 500 Internal Server Error")
+
+	bot := NewBot(Config{ShortenLinks: server.URL + "/?url={url}"})
+	assert.NotNil(t, bot.config.GitHub.Shortener)
+}
@@ -0,0 +1,217 @@
+package telebot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store keeps the state of the standalone server, such as the messages the
+// dedup remembers, for a while.
+type Store interface {
+	// Get returns the value of the key, or false if it's not there or it
+	// expired.
+	Get(key string) (string, bool)
+	// Set keeps the value of the key for the ttl.
+	Set(key string, value string, ttl time.Duration) error
+}
+
+// maxStoreEntries bounds how many keys the MemoryStore keeps.
+const maxStoreEntries = 1000
+
+// storeEntry is a value and when it expires.
+type storeEntry struct {
+	Value   string    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// MemoryStore is a Store that's lost when the server restarts. It's the default
+// one.
+type MemoryStore struct {
+	// now is replaced by the tests.
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]storeEntry
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, entries: map[string]storeEntry{}}
+}
+
+// Get returns the value of the key, unless it expired.
+func (s *MemoryStore) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || !s.now().Before(entry.Expires) {
+		return "", false
+	}
+	return entry.Value, true
+}
+
+// Set keeps the value of the key for the ttl. If the store is full, the
+// expired keys are dropped, or the one that expires the soonest.
+func (s *MemoryStore) Set(key string, value string, ttl time.Duration) error {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key]; !ok && len(s.entries) >= maxStoreEntries {
+		s.forget(now)
+	}
+	s.entries[key] = storeEntry{Value: value, Expires: now.Add(ttl)}
+	return nil
+}
+
+// forget drops the expired keys. If that's not enough to make room, the one
+// that expires the soonest goes too.
+func (s *MemoryStore) forget(now time.Time) {
+	var soonest string
+	var soonestExpires time.Time
+	for key, entry := range s.entries {
+		if !now.Before(entry.Expires) {
+			delete(s.entries, key)
+			continue
+		}
+		if soonestExpires.IsZero() || entry.Expires.Before(soonestExpires) {
+			soonest, soonestExpires = key, entry.Expires
+		}
+	}
+	if len(s.entries) >= maxStoreEntries {
+		delete(s.entries, soonest)
+	}
+}
+
+// fileStoreSweep is how often the FileStore removes the files of the expired
+// keys, since most of them, as the ones of the dedup, are never read again.
+const fileStoreSweep = 10 * time.Minute
+
+// FileStore is a Store that survives restarts, with a file for each key in its
+// directory.
+type FileStore struct {
+	dir string
+	// now is replaced by the tests.
+	now func() time.Time
+
+	mu    sync.Mutex
+	swept time.Time
+}
+
+// NewFileStore returns a store in the directory, creating it if needed. The
+// keys that expired while the server was down are dropped, and the rest are
+// dropped as they expire, every once in a while.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &FileStore{dir: dir, now: time.Now}
+	s.forget()
+	s.swept = s.now()
+	return s, nil
+}
+
+// path returns the file of the key. The keys are hashed, so any of them makes
+// a valid file name.
+func (s *FileStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// Get returns the value of the key, unless it expired.
+func (s *FileStore) Get(key string) (string, bool) {
+	entry, ok := s.read(s.path(key))
+	if !ok {
+		return "", false
+	}
+	return entry.Value, true
+}
+
+// Set keeps the value of the key for the ttl. The file is written aside and
+// then renamed, so it's never read half written.
+func (s *FileStore) Set(key string, value string, ttl time.Duration) error {
+	data, err := json.Marshal(storeEntry{Value: value, Expires: s.now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+
+	s.sweep()
+
+	path := s.path(key)
+	tmp, err := ioutil.TempFile(s.dir, tmpPrefix)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// read returns the entry in the file, removing it if it expired.
+func (s *FileStore) read(path string) (storeEntry, bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return storeEntry{}, false
+	}
+	var entry storeEntry
+	if err := json.Unmarshal(data, &entry); err != nil || !s.now().Before(entry.Expires) {
+		os.Remove(path)
+		return storeEntry{}, false
+	}
+	return entry, true
+}
+
+// tmpPrefix starts the names of the files being written.
+const tmpPrefix = ".tmp-"
+
+// sweep removes the files of the expired keys, unless it was done less than
+// fileStoreSweep ago.
+func (s *FileStore) sweep() {
+	s.mu.Lock()
+	if s.now().Sub(s.swept) < fileStoreSweep {
+		s.mu.Unlock()
+		return
+	}
+	s.swept = s.now()
+	s.mu.Unlock()
+
+	s.forget()
+}
+
+// forget removes the files of the expired keys, and the ones left half written
+// a while ago, as when the server crashes. The ones being written are left
+// alone.
+func (s *FileStore) forget() {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		path := filepath.Join(s.dir, file.Name())
+		switch {
+		case file.IsDir():
+		case strings.HasPrefix(file.Name(), tmpPrefix):
+			if s.now().Sub(file.ModTime()) > fileStoreSweep {
+				os.Remove(path)
+			}
+		default:
+			s.read(path)
+		}
+	}
+}
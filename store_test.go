@@ -0,0 +1,99 @@
+package telebot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	assert.Nil(t, store.Set("a", "1", time.Minute))
+	value, ok := store.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+
+	_, ok = store.Get("b")
+	assert.False(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = store.Get("a")
+	assert.False(t, ok)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telebot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	store, err := NewFileStore(dir)
+	assert.Nil(t, err)
+	store.now = func() time.Time { return now }
+
+	assert.Nil(t, store.Set("a", "1", time.Minute))
+	assert.Nil(t, store.Set("b", "2", time.Hour))
+
+	// What's set survives a restart.
+	restarted, err := NewFileStore(dir)
+	assert.Nil(t, err)
+	value, ok := restarted.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+
+	_, ok = restarted.Get("c")
+	assert.False(t, ok)
+
+	// The expired keys are removed.
+	now = now.Add(time.Minute)
+	_, ok = store.Get("a")
+	assert.False(t, ok)
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1)
+}
+
+func TestFileStoreSweep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telebot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	store, err := NewFileStore(dir)
+	assert.Nil(t, err)
+	store.now = func() time.Time { return now }
+
+	// The keys never read again are removed by the next sweep.
+	for _, key := range []string{"a", "b", "c"} {
+		assert.Nil(t, store.Set(key, "1", time.Minute))
+	}
+	assert.Nil(t, store.Set("kept", "1", time.Hour))
+
+	now = now.Add(time.Minute)
+	assert.Nil(t, store.Set("d", "1", time.Minute))
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 5)
+
+	now = now.Add(fileStoreSweep)
+	assert.Nil(t, store.Set("e", "1", time.Hour))
+	files, _ = ioutil.ReadDir(dir)
+	assert.Len(t, files, 2)
+	_, ok := store.Get("kept")
+	assert.True(t, ok)
+}
+
+func TestDedupFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telebot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store, _ := NewFileStore(dir)
+	newDedup(time.Minute, store).record("123", "hello")
+
+	restarted, _ := NewFileStore(dir)
+	assert.True(t, newDedup(time.Minute, restarted).duplicate("123", "hello"))
+}
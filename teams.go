@@ -0,0 +1,93 @@
+package telebot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/berserktech/telebot/gh"
+)
+
+// TeamsNotifier posts the messages to a Microsoft Teams channel, through the
+// URL of an incoming webhook connector.
+type TeamsNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewTeamsNotifier returns a notifier that posts the messages to the Teams
+// connector at the URL.
+func NewTeamsNotifier(url string) *TeamsNotifier {
+	return &TeamsNotifier{URL: url, Client: &http.Client{Timeout: DefaultNotifierTimeout}}
+}
+
+// teamsCard is the legacy MessageCard the Teams connectors take.
+type teamsCard struct {
+	Type            string        `json:"@type"`
+	Context         string        `json:"@context"`
+	Summary         string        `json:"summary"`
+	ThemeColor      string        `json:"themeColor,omitempty"`
+	Title           string        `json:"title"`
+	Text            string        `json:"text"`
+	PotentialAction []teamsAction `json:"potentialAction,omitempty"`
+}
+
+type teamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []teamsTarget `json:"targets"`
+}
+
+type teamsTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+// teamsColors are the colors of the cards of the urgent messages.
+var teamsColors = map[string]string{
+	gh.SeverityWarning:  "FFA500",
+	gh.SeverityCritical: "D00000",
+}
+
+// newTeamsCard renders the message as a card titled after its repository and
+// event, with a button to the GitHub page of the event, if there's one. The
+// urgent messages are colored after their severity.
+func newTeamsCard(message gh.Message) teamsCard {
+	title := message.Event
+	if message.Repository != "" {
+		title = fmt.Sprintf("%s: %s", message.Repository, message.Event)
+	}
+
+	card := teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    title,
+		ThemeColor: teamsColors[message.Severity],
+		Title:      title,
+		Text:       message.Text,
+	}
+	if message.URL != "" {
+		card.PotentialAction = []teamsAction{{
+			Type:    "OpenUri",
+			Name:    "Open in GitHub",
+			Targets: []teamsTarget{{OS: "default", URI: message.URL}},
+		}}
+	}
+	return card
+}
+
+// Notify posts the message as a card. Any answer but a 2xx is an error.
+func (n *TeamsNotifier) Notify(message gh.Message, chatId string) error {
+	return n.NotifyContext(context.Background(), message, chatId)
+}
+
+// NotifyContext posts the message as a card until the context is done.
+func (n *TeamsNotifier) NotifyContext(ctx context.Context, message gh.Message, chatId string) error {
+	body, err := json.Marshal(newTeamsCard(message))
+	if err != nil {
+		return err
+	}
+
+	return postJSON(ctx, n.Client, "teams", n.URL, body)
+}
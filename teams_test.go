@@ -0,0 +1,50 @@
+package telebot
+
+import (
+	"encoding/json"
+	"github.com/berserktech/telebot/gh"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTeamsNotifier(t *testing.T) {
+	posted := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var card map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&card)
+		posted <- card
+	}))
+	defer server.Close()
+
+	message := gh.Message{
+		Text:       "hello",
+		Event:      "issues",
+		Repository: "Codertocat/Hello-World",
+		URL:        "https://github.com/Codertocat/Hello-World/issues/2",
+	}
+	assert.Nil(t, NewTeamsNotifier(server.URL).Notify(message, "123"))
+
+	card := <-posted
+	assert.Equal(t, "MessageCard", card["@type"])
+	assert.Equal(t, "Codertocat/Hello-World: issues", card["title"])
+	assert.Equal(t, "hello", card["text"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"@type":   "OpenUri",
+		"name":    "Open in GitHub",
+		"targets": []interface{}{map[string]interface{}{"os": "default", "uri": message.URL}},
+	}}, card["potentialAction"])
+}
+
+func TestTeamsNotifierFromConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	bot, client := mockBot(Config{TeamsURL: server.URL})
+	err := bot.send(gh.Message{Text: "hello"}, "123")
+	assert.EqualError(t, err, "telebot: teams answered 400 Bad Request")
+	assert.Len(t, client.sent, 1)
+}
@@ -0,0 +1,203 @@
+package tg
+
+import (
+	"html"
+	"strings"
+)
+
+// The parse modes the messages can be sent with.
+const (
+	ParseModeMarkdown   = "Markdown"
+	ParseModeMarkdownV2 = "MarkdownV2"
+	ParseModeHTML       = "HTML"
+)
+
+// markdownV2Escaper escapes the characters reserved by Telegram's MarkdownV2.
+var markdownV2Escaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)",
+	"~", "\\~", "`", "\\`", ">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-",
+	"=", "\\=", "|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.", "!", "\\!",
+)
+
+// markdownV2CodeEscaper escapes what's reserved inside of MarkdownV2's code
+// and links.
+var markdownV2CodeEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`", ")", "\\)")
+
+// QuotePrefix starts the lines of the quotes of the messages. Markdown has no
+// quotes, so they're only marked there, and they're rendered as quotes in the
+// other parse modes.
+const QuotePrefix = "> "
+
+// Convert renders a message written in Telegram's Markdown, as the messages of
+// the bot are, in the given parse mode. Its bold, italic, code, links and
+// quotes are kept, and everything else is escaped, so that it's shown as it
+// is. Unknown parse modes get the message as it is.
+func Convert(message string, parseMode string) string {
+	var r renderer
+	switch parseMode {
+	case ParseModeHTML:
+		r = htmlRenderer{}
+	case ParseModeMarkdownV2:
+		r = markdownV2Renderer{}
+	default:
+		return message
+	}
+
+	var blocks []string
+	for _, block := range quoteBlocks(message) {
+		if block.quote {
+			blocks = append(blocks, r.quote(convert(block.text, r)))
+		} else {
+			blocks = append(blocks, convert(block.text, r))
+		}
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// block is a run of lines of a message, either quoted or not. The text of the
+// quotes is without their QuotePrefix.
+type block struct {
+	text  string
+	quote bool
+}
+
+// quoteBlocks splits the message into its quotes and the rest. The lines of
+// the code blocks are never quotes.
+func quoteBlocks(message string) []block {
+	var blocks []block
+	inCode := false
+	for _, line := range strings.Split(message, "\n") {
+		isQuote := !inCode && strings.HasPrefix(line, QuotePrefix)
+		if isQuote {
+			line = strings.TrimPrefix(line, QuotePrefix)
+		}
+		if strings.Count(line, "```")%2 == 1 {
+			inCode = !inCode
+		}
+
+		if n := len(blocks); n > 0 && blocks[n-1].quote == isQuote {
+			blocks[n-1].text += "\n" + line
+			continue
+		}
+		blocks = append(blocks, block{text: line, quote: isQuote})
+	}
+	return blocks
+}
+
+// convert renders the Markdown of the text with the renderer.
+func convert(message string, r renderer) string {
+	var b strings.Builder
+	runes := []rune(message)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; {
+		case c == '\\' && i+1 < len(runes) && strings.ContainsRune("_*`[", runes[i+1]):
+			b.WriteString(r.text(string(runes[i+1])))
+			i++
+		case c == '`' && hasPrefix(runes[i:], "```"):
+			end := indexFrom(runes, i+3, "```")
+			if end < 0 {
+				b.WriteString(r.text(string(runes[i:])))
+				return b.String()
+			}
+			b.WriteString(r.pre(string(runes[i+3 : end])))
+			i = end + 2
+		case c == '`' || c == '*' || c == '_':
+			end := indexFrom(runes, i+1, string(c))
+			if end < 0 {
+				b.WriteString(r.text(string(c)))
+				continue
+			}
+			b.WriteString(r.entity(c, string(runes[i+1:end])))
+			i = end
+		case c == '[':
+			textEnd := indexFrom(runes, i+1, "](")
+			urlEnd := -1
+			if textEnd >= 0 {
+				urlEnd = indexFrom(runes, textEnd+2, ")")
+			}
+			if urlEnd < 0 {
+				b.WriteString(r.text("["))
+				continue
+			}
+			b.WriteString(r.link(unescape(string(runes[i+1:textEnd])), string(runes[textEnd+2:urlEnd])))
+			i = urlEnd
+		default:
+			b.WriteString(r.text(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// renderer writes the entities of a message in a parse mode.
+type renderer interface {
+	text(s string) string
+	entity(marker rune, s string) string
+	pre(s string) string
+	link(text string, url string) string
+	// quote renders the text, already rendered, as a quote.
+	quote(s string) string
+}
+
+type htmlRenderer struct{}
+
+func (htmlRenderer) text(s string) string { return html.EscapeString(s) }
+
+func (htmlRenderer) entity(marker rune, s string) string {
+	tag := map[rune]string{'*': "b", '_': "i", '`': "code"}[marker]
+	return "<" + tag + ">" + html.EscapeString(s) + "</" + tag + ">"
+}
+
+func (htmlRenderer) pre(s string) string { return "<pre>" + html.EscapeString(s) + "</pre>" }
+
+func (htmlRenderer) link(text string, url string) string {
+	return `<a href="` + html.EscapeString(url) + `">` + html.EscapeString(text) + "</a>"
+}
+
+func (htmlRenderer) quote(s string) string { return "<blockquote>" + s + "</blockquote>" }
+
+type markdownV2Renderer struct{}
+
+func (markdownV2Renderer) text(s string) string { return markdownV2Escaper.Replace(s) }
+
+func (markdownV2Renderer) entity(marker rune, s string) string {
+	if marker == '`' {
+		return "`" + markdownV2CodeEscaper.Replace(s) + "`"
+	}
+	return string(marker) + markdownV2Escaper.Replace(s) + string(marker)
+}
+
+func (markdownV2Renderer) pre(s string) string {
+	return "```" + markdownV2CodeEscaper.Replace(s) + "```"
+}
+
+func (markdownV2Renderer) link(text string, url string) string {
+	return "[" + markdownV2Escaper.Replace(text) + "](" + markdownV2CodeEscaper.Replace(url) + ")"
+}
+
+func (markdownV2Renderer) quote(s string) string {
+	return ">" + strings.Replace(s, "\n", "\n>", -1)
+}
+
+// unescape drops the backslashes Markdown needs inside of the text of a link.
+func unescape(s string) string {
+	return strings.NewReplacer(`\_`, "_", `\*`, "*", "\\`", "`", `\[`, "[").Replace(s)
+}
+
+// hasPrefix returns true if the runes start with the prefix.
+func hasPrefix(runes []rune, prefix string) bool {
+	p := []rune(prefix)
+	return len(runes) >= len(p) && string(runes[:len(p)]) == prefix
+}
+
+// indexFrom returns where the substring is found in the runes, past from, or
+// -1 if it isn't there.
+func indexFrom(runes []rune, from int, substr string) int {
+	sub := []rune(substr)
+	for i := from; i+len(sub) <= len(runes); i++ {
+		if string(runes[i:i+len(sub)]) == substr {
+			return i
+		}
+	}
+	return -1
+}
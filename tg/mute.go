@@ -0,0 +1,136 @@
+package tg
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// DefaultMuteDuration is used when /mute is sent without a duration.
+const DefaultMuteDuration = time.Hour
+
+// Mute remembers until when the notifications are muted. It's safe to use
+// from different goroutines.
+type Mute struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// For mutes the notifications for the given duration.
+func (m *Mute) For(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.until = time.Now().Add(d)
+}
+
+// Clear unmutes the notifications.
+func (m *Mute) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.until = time.Time{}
+}
+
+// Muted returns true while the notifications are muted.
+func (m *Mute) Muted() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return time.Now().Before(m.until)
+}
+
+// AdminClient is the part of the Telegram Bot API needed to answer the admin
+// commands.
+type AdminClient interface {
+	TelegramClient
+	GetChatMember(config tgbotapi.ChatConfigWithUser) (tgbotapi.ChatMember, error)
+}
+
+// ListenCommands long-polls Telegram for the /mute and /unmute commands sent to
+// the given chats, as in HandleCommand. It blocks for as long as the bot runs.
+func ListenCommands(bot *tgbotapi.BotAPI, mute *Mute, chats []string) error {
+	config := tgbotapi.NewUpdate(0)
+	config.Timeout = 60
+	updates, err := bot.GetUpdatesChan(config)
+	if err != nil {
+		return err
+	}
+
+	for update := range updates {
+		if update.Message == nil {
+			continue
+		}
+		if err := HandleCommand(bot, mute, chats, update.Message); err != nil {
+			log.Print(err)
+		}
+	}
+	return nil
+}
+
+// HandleCommand applies the /mute and /unmute commands, as long as they're
+// sent by an administrator of one of the given chats, by their ID or their
+// username as in "@channel", and replies to them. The mute applies to every
+// chat, so the commands sent anywhere else, and any other message, are ignored.
+func HandleCommand(client AdminClient, mute *Mute, chats []string, message *tgbotapi.Message) error {
+	command := message.Command()
+	if command != "mute" && command != "unmute" {
+		return nil
+	}
+	if message.From == nil || !commandChat(chats, message.Chat) {
+		return nil
+	}
+
+	member, err := client.GetChatMember(tgbotapi.ChatConfigWithUser{
+		ChatID: message.Chat.ID,
+		UserID: message.From.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if !member.IsCreator() && !member.IsAdministrator() {
+		return reply(client, message, "Only the administrators of this chat can do that.")
+	}
+
+	if command == "unmute" {
+		mute.Clear()
+		return reply(client, message, "Notifications unmuted.")
+	}
+
+	d := DefaultMuteDuration
+	if arg := message.CommandArguments(); arg != "" {
+		d, err = time.ParseDuration(arg)
+		if err != nil || d <= 0 {
+			return reply(client, message, fmt.Sprintf("Invalid duration %q, try something like /mute 1h.", arg))
+		}
+	}
+	mute.For(d)
+	return reply(client, message, fmt.Sprintf("Notifications muted for %s.", d))
+}
+
+// commandChat returns true if the chat is one of the chats.
+func commandChat(chats []string, chat *tgbotapi.Chat) bool {
+	if chat == nil {
+		return false
+	}
+	for _, chatId := range chats {
+		if strings.HasPrefix(chatId, "@") {
+			if chat.UserName != "" && strings.EqualFold(chatId, "@"+chat.UserName) {
+				return true
+			}
+			continue
+		}
+		if id, err := parseChatID(chatId); err == nil && id == chat.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func reply(client TelegramClient, message *tgbotapi.Message, text string) error {
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ReplyToMessageID = message.MessageID
+	_, err := client.Send(msg)
+	return err
+}
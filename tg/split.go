@@ -0,0 +1,167 @@
+package tg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitReserve is the room left in each part for its number, as in "(1/3)".
+const splitReserve = len("\n(999/999)")
+
+// closeFence closes the code blocks cut in two.
+const closeFence = "\n```"
+
+// Split cuts the message into parts of at most max characters, numbered as in
+// "(1/3)". It cuts at the paragraphs, then at the lines, then at the spaces,
+// but never inside a code block, an inline code or a link, so that their
+// Markdown isn't broken. A code block longer than a part is cut at a line
+// instead, closed, and opened again in the next part. An inline code or a link
+// longer than a part is cut anywhere, since it can't be reopened. The messages
+// that fit are returned as they are.
+func Split(message string, max int) []string {
+	runes := []rune(message)
+	if len(runes) <= max {
+		return []string{message}
+	}
+
+	safe, blocks := safeCuts(runes)
+	limit := max - splitReserve
+	var parts []string
+	start := 0
+	// reopen starts the part when it goes on with the code block of the
+	// previous one.
+	var reopen []rune
+	for len(runes)-start > limit-len(reopen) {
+		end := start + limit - len(reopen)
+		cut := bestCut(runes, safe, start, end)
+		if safe[cut] || blocks[cut] < 0 {
+			parts = append(parts, string(reopen)+strings.TrimRight(string(runes[start:cut]), " \n"))
+			reopen = nil
+			for start = cut; start < len(runes) && (runes[start] == '\n' || runes[start] == ' '); start++ {
+			}
+			continue
+		}
+
+		// The code block doesn't fit in a part, so it's closed at one of its
+		// lines past the opening one.
+		fence := fenceLine(runes, blocks[cut])
+		from := blocks[cut] + len(fence) + 1
+		if from < start {
+			from = start
+		}
+		cut = lineCut(runes, from, end-len(closeFence))
+		parts = append(parts, string(reopen)+strings.TrimRight(string(runes[start:cut]), "\n")+closeFence)
+		reopen = append(fence, '\n')
+		start = cut
+	}
+	if start < len(runes) {
+		parts = append(parts, string(reopen)+string(runes[start:]))
+	}
+
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%s\n(%d/%d)", parts[i], i+1, len(parts))
+	}
+	return parts
+}
+
+// bestCut returns where to cut the runes between start and end: the last
+// paragraph past the half of them, or else the last line, the last space, or
+// anything outside of an entity. It's the end if nothing is safe.
+func bestCut(runes []rune, safe []bool, start int, end int) int {
+	boundaries := []func(i int) bool{
+		func(i int) bool { return i-start > (end-start)/2 && i >= 2 && runes[i-1] == '\n' && runes[i-2] == '\n' },
+		func(i int) bool { return runes[i-1] == '\n' },
+		func(i int) bool { return runes[i-1] == ' ' },
+		func(i int) bool { return true },
+	}
+	for _, boundary := range boundaries {
+		for i := end; i > start; i-- {
+			if safe[i] && boundary(i) {
+				return i
+			}
+		}
+	}
+	return end
+}
+
+// lineCut returns where to cut the code block between start and end: the last
+// line, or else the end.
+func lineCut(runes []rune, start int, end int) int {
+	for i := end; i > start; i-- {
+		if runes[i-1] == '\n' {
+			return i
+		}
+	}
+	return end
+}
+
+// fenceLine returns the line opening the code block at i, as in "```go", or
+// only its backticks if the block is in a single line.
+func fenceLine(runes []rune, i int) []rune {
+	for j := i + 3; j < len(runes) && runes[j] != '`'; j++ {
+		if runes[j] == '\n' {
+			return append([]rune{}, runes[i:j]...)
+		}
+	}
+	return []rune("```")
+}
+
+// safeCuts returns whether the message can be cut before each one of its runes,
+// which is anywhere but inside a code block, an inline code or a link, and
+// right after an escaping backslash. It also returns where the code block
+// around each rune starts, or -1 if it isn't in one.
+func safeCuts(runes []rune) ([]bool, []int) {
+	safe := make([]bool, len(runes)+1)
+	blocks := make([]int, len(runes)+1)
+	for i := range safe {
+		safe[i] = true
+		blocks[i] = -1
+	}
+
+	for i := 0; i < len(runes); i++ {
+		end := entityEnd(runes, i)
+		if end < 0 && runes[i] == '\\' {
+			end = i + 2
+		}
+		if end < 0 {
+			continue
+		}
+		block := strings.HasPrefix(string(runes[i:end]), "```")
+		for j := i + 1; j < end && j < len(safe); j++ {
+			safe[j] = false
+			if block {
+				blocks[j] = i
+			}
+		}
+		i = end - 1
+	}
+	return safe, blocks
+}
+
+// entityEnd returns where the entity starting at i ends, or -1 if there's none,
+// or it's never closed.
+func entityEnd(runes []rune, i int) int {
+	if runes[i] != '`' && runes[i] != '[' {
+		return -1
+	}
+	rest := string(runes[i:])
+	switch {
+	case strings.HasPrefix(rest, "```"):
+		if j := strings.Index(rest[3:], "```"); j >= 0 {
+			return i + len([]rune(rest[:3+j+3]))
+		}
+	case runes[i] == '`':
+		if j := strings.IndexRune(rest[1:], '`'); j >= 0 {
+			return i + len([]rune(rest[:1+j+1]))
+		}
+	case runes[i] == '[':
+		text := strings.Index(rest, "](")
+		if text < 0 || strings.Contains(rest[:text], "\n") {
+			return -1
+		}
+		if j := strings.IndexRune(rest[text:], ')'); j >= 0 {
+			return i + len([]rune(rest[:text+j+1]))
+		}
+	}
+	return -1
+}
@@ -1,27 +1,284 @@
+// Package tg handles telegram-related actions.
 package tg
 
 import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
-// Based on: https://github.com/go-telegram-bot-api/telegram-bot-api
-// TODO: The configuration we set here is probably better in a configuration file.
+// TelegramClient is the part of the Telegram Bot API we use. It's implemented
+// by *tgbotapi.BotAPI, and it allows the tests to check what's sent without
+// reaching Telegram.
+type TelegramClient interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+}
+
+// Send sends the message to the given chat, through a client made for it
+// without a proxy other than the HTTPS_PROXY.
 func Send(message string, token string, chatId string) error {
-	bot, err := tgbotapi.NewBotAPI(token)
+	bot, err := NewBot(token, "")
 	if err != nil {
 		return err
 	}
+	return SendMessage(bot, message, chatId)
+}
+
+// NewBot returns a client for the Telegram Bot API. If a proxy URL is given,
+// every request to Telegram goes through it, otherwise the HTTPS_PROXY
+// environment variable is honored.
+// Based on: https://github.com/go-telegram-bot-api/telegram-bot-api
+func NewBot(token string, proxy string) (*tgbotapi.BotAPI, error) {
+	return NewBotWithTimeout(token, proxy, 0)
+}
+
+// NewBotWithTimeout returns a client like NewBot's, whose requests fail once
+// they take longer than the timeout, unless it's zero. The Telegram Bot API
+// library takes no context, so it's the timeout of its http.Client, for each
+// request on its own.
+func NewBotWithTimeout(token string, proxy string, timeout time.Duration) (*tgbotapi.BotAPI, error) {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if proxy != "" {
+		proxyURL, err := ParseProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	bot, err := tgbotapi.NewBotAPIWithClient(token, &http.Client{Transport: transport, Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
 	bot.Debug = true
-	i64ID, err := strconv.ParseInt(chatId, 10, 64)
+	return bot, nil
+}
+
+// ParseProxy checks that the proxy is an absolute http, https or socks5 URL.
+func ParseProxy(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("tg: invalid proxy, %s", err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("tg: invalid proxy scheme, %q", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("tg: invalid proxy, missing host")
+	}
+
+	return proxyURL, nil
+}
+
+// Options tweak how the messages are sent. The zero value is the default
+// behavior.
+type Options struct {
+	// Preview shows the preview of the first link of the message, which
+	// Telegram would show by default, but is too noisy for most events.
+	Preview bool
+	// Silent sends the message without a notification sound.
+	Silent bool
+	// Buttons are the links shown under the message.
+	Buttons []Button
+	// MarkdownOnly drops the messages whose Markdown Telegram can't parse,
+	// instead of sending them once again as plain text.
+	MarkdownOnly bool
+	// Split sends the messages longer than MaxMessageLength in as many parts
+	// as needed, instead of letting Telegram reject them. See Split.
+	Split bool
+	// ParseMode is the parse mode the messages are sent with. They're written
+	// in Markdown, the default, and converted to the other ones. See Convert.
+	ParseMode string
+}
+
+// parseMode returns the parse mode the messages are sent with.
+func (o Options) parseMode() string {
+	switch o.ParseMode {
+	case ParseModeHTML, ParseModeMarkdownV2:
+		return o.ParseMode
+	default:
+		return ParseModeMarkdown
+	}
+}
+
+// fallsBack returns true if the message has to be sent once again as plain
+// text, since Telegram couldn't parse its Markdown.
+func (o Options) fallsBack(err error) bool {
+	return err != nil && !o.MarkdownOnly && strings.Contains(err.Error(), "can't parse entities")
+}
+
+// Button is a link shown under the message.
+type Button struct {
+	Text string
+	URL  string
+}
+
+// keyboard returns the buttons as a single row, or nil if there are none.
+func (o Options) keyboard() interface{} {
+	if len(o.Buttons) == 0 {
+		return nil
+	}
+	var row []tgbotapi.InlineKeyboardButton
+	for _, button := range o.Buttons {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonURL(button.Text, button.URL))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// SendMessage sends the message to the given chat through the client.
+func SendMessage(client TelegramClient, message string, chatId string) error {
+	return SendMessageWith(client, message, chatId, Options{})
+}
+
+// SendMessageWith sends the message to the given chat through the client, as
+// set by the options. The length of the message is the one it has once
+// converted to the parse mode, which is what's sent.
+func SendMessageWith(client TelegramClient, message string, chatId string, o Options) error {
+	if !o.Split || utf8.RuneCountInString(Convert(message, o.parseMode())) <= MaxMessageLength {
+		return sendMessage(client, message, chatId, o)
+	}
+
+	// The buttons go under the last part.
+	parts := splitConverted(message, o.parseMode())
+	buttons := o.Buttons
+	o.Buttons = nil
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			o.Buttons = buttons
+		}
+		if err := sendMessage(client, part, chatId, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitConverted splits the message into parts that fit in MaxMessageLength
+// once converted to the parse mode, as each one of them is. The parts are made
+// shorter until they all do, for as long as they can be.
+func splitConverted(message string, parseMode string) []string {
+	max := MaxMessageLength
+	for {
+		parts := Split(message, max)
+		longest := 0
+		for _, part := range parts {
+			if n := utf8.RuneCountInString(Convert(part, parseMode)); n > longest {
+				longest = n
+			}
+		}
+		if longest <= MaxMessageLength || max <= 2*splitReserve {
+			return parts
+		}
+		max = max * MaxMessageLength / longest
+	}
+}
+
+// sendMessage sends a single message, falling back to plain text if its
+// Markdown can't be parsed.
+func sendMessage(client TelegramClient, message string, chatId string, o Options) error {
+	chat, err := baseChat(chatId)
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.MessageConfig{BaseChat: chat, Text: Convert(message, o.parseMode())}
+	msg.ParseMode = o.parseMode()
+	msg.DisableWebPagePreview = !o.Preview
+	msg.DisableNotification = o.Silent
+	msg.ReplyMarkup = o.keyboard()
+	_, err = client.Send(msg)
+	if o.fallsBack(err) {
+		log.Printf("tg: %s, sending the message as plain text", err)
+		msg.ParseMode = ""
+		msg.Text = message
+		_, err = client.Send(msg)
+	}
+	return err
+}
+
+// MaxCaptionLength is the longest caption Telegram accepts for a photo.
+const MaxCaptionLength = 1024
+
+// MaxMessageLength is the longest message Telegram accepts.
+const MaxMessageLength = 4096
+
+// SendPhoto sends the photo at the given URL with the message as its caption.
+// Messages too long to be a caption are sent as text instead.
+func SendPhoto(client TelegramClient, photoURL string, message string, chatId string) error {
+	return SendPhotoWith(client, photoURL, message, chatId, Options{})
+}
+
+// SendPhotoWith is SendPhoto, as set by the options.
+func SendPhotoWith(client TelegramClient, photoURL string, message string, chatId string, o Options) error {
+	if utf8.RuneCountInString(message) > MaxCaptionLength {
+		return SendMessageWith(client, message, chatId, o)
+	}
+
+	chat, err := baseChat(chatId)
 	if err != nil {
 		return err
 	}
+	// Telegram downloads the photo by itself when it gets a URL.
+	photo := tgbotapi.NewPhotoShare(0, photoURL)
+	photo.BaseChat = chat
+	photo.Caption = Convert(message, o.parseMode())
+	photo.ParseMode = o.parseMode()
+	photo.DisableNotification = o.Silent
+	photo.ReplyMarkup = o.keyboard()
+	_, err = client.Send(photo)
+	if o.fallsBack(err) {
+		log.Printf("tg: %s, sending the caption as plain text", err)
+		photo.ParseMode = ""
+		photo.Caption = message
+		_, err = client.Send(photo)
+	}
+	return err
+}
+
+// UserChatPrefix marks the IDs of the private chats with the users, as in
+// "user:123456". Unlike the IDs of the groups, they're positive, so they're sent
+// to as they are.
+const UserChatPrefix = "user:"
+
+// UserChat returns the chat ID of the private chat with the user of the ID. The
+// usernames, and the IDs that are marked already, are returned as they are.
+func UserChat(chatId string) string {
+	if chatId == "" || strings.HasPrefix(chatId, "@") || strings.HasPrefix(chatId, UserChatPrefix) {
+		return chatId
+	}
+	return UserChatPrefix + chatId
+}
+
+// baseChat returns who the message is sent to. The chats can be given by their
+// ID, by their username as in "@channel", or as a UserChat.
+func baseChat(chatId string) (tgbotapi.BaseChat, error) {
+	if strings.HasPrefix(chatId, "@") {
+		return tgbotapi.BaseChat{ChannelUsername: chatId}, nil
+	}
+	id, err := parseChatID(chatId)
+	if err != nil {
+		return tgbotapi.BaseChat{}, err
+	}
+	return tgbotapi.BaseChat{ChatID: id}, nil
+}
+
+func parseChatID(chatId string) (int64, error) {
+	if id := strings.TrimPrefix(chatId, UserChatPrefix); id != chatId {
+		return strconv.ParseInt(id, 10, 64)
+	}
+	i64ID, err := strconv.ParseInt(chatId, 10, 64)
+	if err != nil {
+		return 0, err
+	}
 	// All group chat IDs are negative numbers, apparently
-	msg := tgbotapi.NewMessage(-i64ID, message)
-	msg.ParseMode = "Markdown"
-	msg.DisableWebPagePreview = true
-	bot.Send(msg)
-	return nil
+	return -i64ID, nil
 }
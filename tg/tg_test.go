@@ -0,0 +1,403 @@
+package tg
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// mockClient records the messages it's asked to send, instead of sending them.
+// The sends fail with the errs, one at a time, and then with err.
+type mockClient struct {
+	sent []tgbotapi.Chattable
+	err  error
+	errs []error
+}
+
+func (m *mockClient) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	m.sent = append(m.sent, c)
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		return tgbotapi.Message{}, err
+	}
+	return tgbotapi.Message{}, m.err
+}
+
+func TestNewBotWithTimeout(t *testing.T) {
+	// The proxy never answers, like a Telegram that hangs.
+	done := make(chan struct{})
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+		}
+	}))
+	defer proxy.Close()
+	defer close(done)
+
+	start := time.Now()
+	_, err := NewBotWithTimeout("token", proxy.URL, 20*time.Millisecond)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Client.Timeout exceeded")
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestSendMessage(t *testing.T) {
+	client := &mockClient{}
+	err := SendMessage(client, "hello", "123")
+	assert.Nil(t, err)
+
+	assert.Len(t, client.sent, 1)
+	msg := client.sent[0].(tgbotapi.MessageConfig)
+	assert.Equal(t, int64(-123), msg.ChatID)
+	assert.Equal(t, "hello", msg.Text)
+	assert.Equal(t, "Markdown", msg.ParseMode)
+	assert.True(t, msg.DisableWebPagePreview)
+}
+
+func TestSendMessageUserChat(t *testing.T) {
+	client := &mockClient{}
+	assert.Nil(t, SendMessage(client, "hello", UserChat("123456789")))
+	assert.Nil(t, SendMessage(client, "hello", "123456789"))
+
+	// The private chats are positive, and the groups negative.
+	assert.Equal(t, int64(123456789), client.sent[0].(tgbotapi.MessageConfig).ChatID)
+	assert.Equal(t, int64(-123456789), client.sent[1].(tgbotapi.MessageConfig).ChatID)
+	assert.Equal(t, "user:1", UserChat(UserChat("1")))
+	assert.Equal(t, "@channel", UserChat("@channel"))
+}
+
+func TestSendMessagePlainFallback(t *testing.T) {
+	client := &mockClient{errs: []error{errors.New("Bad Request: can't parse entities: Can't find end of the entity starting at byte offset 12")}}
+	err := SendMessage(client, "hello *world", "123")
+	assert.Nil(t, err)
+
+	assert.Len(t, client.sent, 2)
+	assert.Equal(t, "Markdown", client.sent[0].(tgbotapi.MessageConfig).ParseMode)
+	assert.Equal(t, "", client.sent[1].(tgbotapi.MessageConfig).ParseMode)
+	assert.Equal(t, "hello *world", client.sent[1].(tgbotapi.MessageConfig).Text)
+}
+
+func TestSendPhotoPlainFallback(t *testing.T) {
+	client := &mockClient{errs: []error{errors.New("Bad Request: can't parse entities")}}
+	err := SendPhoto(client, "https://example.com/a.png", "hello *world", "123")
+	assert.Nil(t, err)
+
+	assert.Len(t, client.sent, 2)
+	assert.Equal(t, "", client.sent[1].(tgbotapi.PhotoConfig).ParseMode)
+}
+
+func TestSendMessageMarkdownOnly(t *testing.T) {
+	client := &mockClient{err: errors.New("Bad Request: can't parse entities")}
+	err := SendMessageWith(client, "hello *world", "123", Options{MarkdownOnly: true})
+	assert.EqualError(t, err, "Bad Request: can't parse entities")
+	assert.Len(t, client.sent, 1)
+}
+
+func TestSendMessageSplit(t *testing.T) {
+	line := strings.Repeat("a", 99) + "\n"
+	message := strings.Repeat(line, 110)
+
+	client := &mockClient{}
+	err := SendMessageWith(client, message, "123", Options{Split: true, Buttons: []Button{{Text: "Open PR", URL: "https://github.com"}}})
+	assert.Nil(t, err)
+
+	assert.Len(t, client.sent, 3)
+	for i, sent := range client.sent {
+		msg := sent.(tgbotapi.MessageConfig)
+		assert.True(t, len(msg.Text) <= MaxMessageLength)
+		assert.True(t, strings.HasSuffix(msg.Text, fmt.Sprintf("\n(%d/3)", i+1)))
+		assert.Equal(t, i == 2, msg.ReplyMarkup != nil)
+	}
+	assert.Equal(t, strings.Repeat(line, 40)+"(1/3)", client.sent[0].(tgbotapi.MessageConfig).Text)
+}
+
+func TestSendMessageSplitConverted(t *testing.T) {
+	// Each dot is escaped in MarkdownV2, and the ampersands in HTML.
+	for mode, c := range map[string]string{ParseModeMarkdownV2: ".", ParseModeHTML: "&"} {
+		line := strings.Repeat("a"+c, 49) + "\n"
+		message := strings.Repeat(line, 40)
+		assert.True(t, len(message) <= MaxMessageLength)
+
+		client := &mockClient{}
+		err := SendMessageWith(client, message, "123", Options{Split: true, ParseMode: mode})
+		assert.Nil(t, err)
+
+		assert.True(t, len(client.sent) > 1, mode)
+		for _, sent := range client.sent {
+			assert.True(t, utf8.RuneCountInString(sent.(tgbotapi.MessageConfig).Text) <= MaxMessageLength, mode)
+		}
+	}
+}
+
+func TestSendMessageWithoutSplit(t *testing.T) {
+	client := &mockClient{}
+	err := SendMessageWith(client, strings.Repeat("a", MaxMessageLength+1), "123", Options{})
+	assert.Nil(t, err)
+	assert.Len(t, client.sent, 1)
+}
+
+func TestSplitEntities(t *testing.T) {
+	code := "```\n" + strings.Repeat("x\n", 20) + "```"
+	link := "[" + strings.Repeat("link ", 5) + "](https://github.com)"
+
+	parts := Split(strings.Repeat("a ", 10)+code+" "+link+" b", 60)
+	for _, part := range parts {
+		assert.True(t, len([]rune(part)) <= 60)
+		// Each part has no code block, or a whole one.
+		assert.True(t, strings.Count(part, "```") != 1, part)
+		assert.Equal(t, strings.Count(part, "["), strings.Count(part, "](https://github.com)"), part)
+	}
+
+	assert.Equal(t, []string{"short"}, Split("short", 60))
+}
+
+func TestSplitLongCodeBlock(t *testing.T) {
+	lines := ""
+	for i := 0; i < 30; i++ {
+		lines += fmt.Sprintf("  line %02d\n", i)
+	}
+	message := "Logs:\n```go\n" + lines + "```"
+
+	parts := Split(message, 120)
+	assert.Len(t, parts, 4)
+	assert.Equal(t, "Logs:\n(1/4)", parts[0])
+	code := ""
+	for i, part := range parts[1:] {
+		assert.True(t, len([]rune(part)) <= 120, part)
+		// Each part opens the code block again, and closes it.
+		body := strings.TrimSuffix(part, fmt.Sprintf("\n```\n(%d/4)", i+2))
+		assert.True(t, strings.HasPrefix(body, "```go\n"), part)
+		code += strings.TrimPrefix(body, "```go\n") + "\n"
+	}
+	// The lines are kept whole, with their indentation.
+	assert.Equal(t, lines, code)
+}
+
+func TestConvert(t *testing.T) {
+	message := "*octocat* commented on `a<b` [PR #1](https://github.com/o/r/pull/1) in my\\_repo: 1 + 1 = 2"
+
+	assert.Equal(t, "<b>octocat</b> commented on <code>a&lt;b</code> <a href=\"https://github.com/o/r/pull/1\">PR #1</a> in my_repo: 1 + 1 = 2", Convert(message, ParseModeHTML))
+	assert.Equal(t, "*octocat* commented on `a<b` [PR \\#1](https://github.com/o/r/pull/1) in my\\_repo: 1 \\+ 1 \\= 2", Convert(message, ParseModeMarkdownV2))
+	assert.Equal(t, message, Convert(message, ParseModeMarkdown))
+	assert.Equal(t, "<pre>x &amp; y</pre> 2 * 3", Convert("```x & y``` 2 * 3", ParseModeHTML))
+}
+
+func TestConvertQuote(t *testing.T) {
+	message := "*octocat* commented:\n\n> a <b>\n> c.\n\nhttps://github.com/o/r/issues/2"
+
+	assert.Equal(t, "<b>octocat</b> commented:\n\n<blockquote>a &lt;b&gt;\nc.</blockquote>\n\nhttps://github.com/o/r/issues/2", Convert(message, ParseModeHTML))
+	assert.Equal(t, "*octocat* commented:\n\n>a <b\\>\n>c\\.\n\nhttps://github\\.com/o/r/issues/2", Convert(message, ParseModeMarkdownV2))
+	assert.Equal(t, message, Convert(message, ParseModeMarkdown))
+
+	// The lines of the code blocks aren't quotes.
+	assert.Equal(t, "<pre>\n&gt; a\n</pre>", Convert("```\n> a\n```", ParseModeHTML))
+}
+
+func TestSendMessageParseMode(t *testing.T) {
+	client := &mockClient{}
+	assert.Nil(t, SendMessageWith(client, "*hello*", "123", Options{ParseMode: ParseModeHTML}))
+	assert.Nil(t, SendMessageWith(client, "*hello*", "123", Options{}))
+
+	assert.Equal(t, "HTML", client.sent[0].(tgbotapi.MessageConfig).ParseMode)
+	assert.Equal(t, "<b>hello</b>", client.sent[0].(tgbotapi.MessageConfig).Text)
+	assert.Equal(t, "Markdown", client.sent[1].(tgbotapi.MessageConfig).ParseMode)
+	assert.Equal(t, "*hello*", client.sent[1].(tgbotapi.MessageConfig).Text)
+}
+
+func TestSendMessageWithPreview(t *testing.T) {
+	client := &mockClient{}
+	err := SendMessageWith(client, "hello", "123", Options{Preview: true})
+	assert.Nil(t, err)
+
+	msg := client.sent[0].(tgbotapi.MessageConfig)
+	assert.False(t, msg.DisableWebPagePreview)
+}
+
+func TestSendPhotoWithSilent(t *testing.T) {
+	client := &mockClient{}
+	err := SendPhotoWith(client, "https://example.com/screenshot.png", "hello", "123", Options{Silent: true})
+	assert.Nil(t, err)
+
+	photo := client.sent[0].(tgbotapi.PhotoConfig)
+	assert.True(t, photo.DisableNotification)
+}
+
+func TestSendMessageChannelUsername(t *testing.T) {
+	client := &mockClient{}
+	err := SendMessage(client, "hello", "@berserktech")
+	assert.Nil(t, err)
+
+	msg := client.sent[0].(tgbotapi.MessageConfig)
+	assert.Equal(t, "@berserktech", msg.ChannelUsername)
+	assert.Equal(t, int64(0), msg.ChatID)
+}
+
+func TestSendMessageWithButtons(t *testing.T) {
+	client := &mockClient{}
+	buttons := []Button{
+		{Text: "Open PR", URL: "https://github.com/Codertocat/Hello-World/pull/1"},
+		{Text: "View Diff", URL: "https://github.com/Codertocat/Hello-World/pull/1/files"},
+	}
+	err := SendMessageWith(client, "hello", "123", Options{Buttons: buttons})
+	assert.Nil(t, err)
+
+	keyboard := client.sent[0].(tgbotapi.MessageConfig).ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	row := keyboard.InlineKeyboard[0]
+	assert.Equal(t, "Open PR", row[0].Text)
+	assert.Equal(t, "https://github.com/Codertocat/Hello-World/pull/1", *row[0].URL)
+	assert.Equal(t, "View Diff", row[1].Text)
+	assert.Equal(t, "https://github.com/Codertocat/Hello-World/pull/1/files", *row[1].URL)
+}
+
+func TestSendMessageWithoutButtons(t *testing.T) {
+	client := &mockClient{}
+	err := SendMessage(client, "hello", "123")
+	assert.Nil(t, err)
+	assert.Nil(t, client.sent[0].(tgbotapi.MessageConfig).ReplyMarkup)
+}
+
+func TestSendPhoto(t *testing.T) {
+	client := &mockClient{}
+	err := SendPhoto(client, "https://example.com/screenshot.png", "hello", "123")
+	assert.Nil(t, err)
+
+	photo := client.sent[0].(tgbotapi.PhotoConfig)
+	assert.Equal(t, int64(-123), photo.ChatID)
+	assert.Equal(t, "https://example.com/screenshot.png", photo.FileID)
+	assert.Equal(t, "hello", photo.Caption)
+}
+
+func TestSendPhotoCaptionTooLong(t *testing.T) {
+	client := &mockClient{}
+	message := strings.Repeat("a", MaxCaptionLength+1)
+	err := SendPhoto(client, "https://example.com/screenshot.png", message, "123")
+	assert.Nil(t, err)
+
+	msg := client.sent[0].(tgbotapi.MessageConfig)
+	assert.Equal(t, message, msg.Text)
+}
+
+// Intentional failures:
+
+func TestSendMessageInvalidChatID(t *testing.T) {
+	client := &mockClient{}
+	err := SendMessage(client, "hello", "not a number")
+	assert.NotNil(t, err)
+	assert.Len(t, client.sent, 0)
+}
+
+func TestSendMessageClientError(t *testing.T) {
+	client := &mockClient{err: errors.New("telegram is down")}
+	err := SendMessage(client, "hello", "123")
+	assert.Equal(t, errors.New("telegram is down"), err)
+}
+
+func TestParseProxy(t *testing.T) {
+	proxyURL, err := ParseProxy("http://proxy.example.com:3128")
+	assert.Nil(t, err)
+	assert.Equal(t, "proxy.example.com:3128", proxyURL.Host)
+}
+
+func TestParseProxyInvalidScheme(t *testing.T) {
+	_, err := ParseProxy("ftp://proxy.example.com")
+	assert.Equal(t, errors.New(`tg: invalid proxy scheme, "ftp"`), err)
+}
+
+func TestParseProxyMissingHost(t *testing.T) {
+	_, err := ParseProxy("proxy.example.com:3128")
+	assert.NotNil(t, err)
+}
+
+// mockAdminClient answers GetChatMember with the given status.
+type mockAdminClient struct {
+	mockClient
+	status string
+}
+
+func (m *mockAdminClient) GetChatMember(c tgbotapi.ChatConfigWithUser) (tgbotapi.ChatMember, error) {
+	return tgbotapi.ChatMember{Status: m.status}, nil
+}
+
+// commandChats are the chats the commands are accepted from, the one of the
+// commands among them.
+var commandChats = []string{"456", "123"}
+
+func command(text string) *tgbotapi.Message {
+	command := strings.SplitN(text, " ", 2)[0]
+	return &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: 42},
+		Chat:      &tgbotapi.Chat{ID: -123},
+		Text:      text,
+		Entities:  &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}},
+	}
+}
+
+func TestHandleCommandMute(t *testing.T) {
+	client := &mockAdminClient{status: "administrator"}
+	mute := &Mute{}
+
+	err := HandleCommand(client, mute, commandChats, command("/mute 1h"))
+	assert.Nil(t, err)
+	assert.True(t, mute.Muted())
+	assert.Equal(t, "Notifications muted for 1h0m0s.", client.sent[0].(tgbotapi.MessageConfig).Text)
+
+	err = HandleCommand(client, mute, commandChats, command("/unmute"))
+	assert.Nil(t, err)
+	assert.False(t, mute.Muted())
+}
+
+func TestHandleCommandMuteDefaultDuration(t *testing.T) {
+	client := &mockAdminClient{status: "creator"}
+	mute := &Mute{}
+
+	err := HandleCommand(client, mute, commandChats, command("/mute"))
+	assert.Nil(t, err)
+	assert.True(t, mute.Muted())
+}
+
+// Intentional failures:
+
+func TestHandleCommandMuteNotAdmin(t *testing.T) {
+	client := &mockAdminClient{status: "member"}
+	mute := &Mute{}
+
+	err := HandleCommand(client, mute, commandChats, command("/mute 1h"))
+	assert.Nil(t, err)
+	assert.False(t, mute.Muted())
+	assert.Equal(t, "Only the administrators of this chat can do that.", client.sent[0].(tgbotapi.MessageConfig).Text)
+}
+
+func TestHandleCommandMuteOtherChat(t *testing.T) {
+	client := &mockAdminClient{status: "creator"}
+	mute := &Mute{}
+
+	err := HandleCommand(client, mute, []string{"456", "@channel"}, command("/mute 1h"))
+	assert.Nil(t, err)
+	assert.False(t, mute.Muted())
+	assert.Len(t, client.sent, 0)
+
+	channel := command("/mute 1h")
+	channel.Chat = &tgbotapi.Chat{ID: -789, UserName: "Channel"}
+	err = HandleCommand(client, mute, []string{"456", "@channel"}, channel)
+	assert.Nil(t, err)
+	assert.True(t, mute.Muted())
+}
+
+func TestHandleCommandMuteInvalidDuration(t *testing.T) {
+	client := &mockAdminClient{status: "administrator"}
+	mute := &Mute{}
+
+	err := HandleCommand(client, mute, commandChats, command("/mute forever"))
+	assert.Nil(t, err)
+	assert.False(t, mute.Muted())
+}
@@ -0,0 +1,69 @@
+package telebot
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle caps how many messages of each kind of event, for each repository,
+// are sent to a chat within a window. The rest are dropped, and counted, so
+// that a single summary of them is sent once the window is over.
+type throttle struct {
+	limit  int
+	window time.Duration
+	// suppressed is called with how many messages were dropped in a window,
+	// if any.
+	suppressed func(chatId string, repo string, event string, count int)
+
+	mu      sync.Mutex
+	windows map[string]*throttleWindow
+}
+
+// throttleWindow counts the messages of a kind of event within a window.
+type throttleWindow struct {
+	chatId, repo, event string
+	sent, dropped       int
+}
+
+func newThrottle(limit int, window time.Duration, suppressed func(string, string, string, int)) *throttle {
+	return &throttle{
+		limit:      limit,
+		window:     window,
+		suppressed: suppressed,
+		windows:    map[string]*throttleWindow{},
+	}
+}
+
+// allow returns true if the message can be sent. The window starts with the
+// first message of its kind.
+func (t *throttle) allow(chatId string, repo string, event string) bool {
+	key := chatId + "\n" + repo + "\n" + event
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.windows[key]
+	if !ok {
+		w = &throttleWindow{chatId: chatId, repo: repo, event: event}
+		t.windows[key] = w
+		time.AfterFunc(t.window, func() { t.reset(key) })
+	}
+	if w.sent < t.limit {
+		w.sent++
+		return true
+	}
+	w.dropped++
+	return false
+}
+
+// reset ends the window, reporting the messages dropped within it.
+func (t *throttle) reset(key string) {
+	t.mu.Lock()
+	w := t.windows[key]
+	delete(t.windows, key)
+	t.mu.Unlock()
+
+	if w.dropped > 0 {
+		t.suppressed(w.chatId, w.repo, w.event, w.dropped)
+	}
+}
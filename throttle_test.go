@@ -0,0 +1,49 @@
+package telebot
+
+import (
+	"github.com/berserktech/telebot/tg"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	counts := make(chan int, 10)
+	th := newThrottle(2, 20*time.Millisecond, func(chatId, repo, event string, count int) { counts <- count })
+
+	assert.True(t, th.allow("123", "Codertocat/Hello-World", "status"))
+	assert.True(t, th.allow("123", "Codertocat/Hello-World", "status"))
+	assert.True(t, th.allow("123", "Codertocat/Hello-World", "issues"))
+	assert.True(t, th.allow("456", "Codertocat/Hello-World", "status"))
+	for i := 0; i < 5; i++ {
+		assert.False(t, th.allow("123", "Codertocat/Hello-World", "status"))
+	}
+
+	assert.Equal(t, 5, <-counts)
+	select {
+	case count := <-counts:
+		t.Fatalf("unexpected summary of %d messages", count)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.True(t, th.allow("123", "Codertocat/Hello-World", "status"))
+}
+
+func TestHandlerThrottle(t *testing.T) {
+	client := &mockClient{}
+	bot := NewServerBot(Config{ThrottleLimit: 1, ThrottleWindow: 20 * time.Millisecond, MaxBodyBytes: DefaultMaxBodyBytes})
+	bot.newClient = func() (tg.TelegramClient, error) { return client, nil }
+
+	for i := 0; i < 4; i++ {
+		request := httptest.NewRequest("POST", "/", strings.NewReader(`{"zen": "Favor focus over features."}`))
+		request.Header.Add("X-GitHub-Event", "ping")
+		bot.Handler("123")(httptest.NewRecorder(), request)
+	}
+	time.Sleep(50 * time.Millisecond)
+	bot.queue.wait()
+
+	assert.Len(t, client.sent, 2)
+	assert.Equal(t, "3 more `ping` events suppressed", client.sent[1].(tgbotapi.MessageConfig).Text)
+}